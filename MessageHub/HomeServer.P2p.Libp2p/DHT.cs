@@ -6,6 +6,8 @@ namespace MessageHub.HomeServer.P2p.Libp2p;
 public class DHTConfig
 {
     public string[]? BootstrapPeers { get; init; }
+    public string? Mode { get; init; }
+    public string? ProtocolPrefix { get; init; }
 }
 
 public sealed class DHT : IDisposable
@@ -1,7 +1,16 @@
 using MessageHub.HomeServer.P2p.Libp2p.Native;
+using MessageHub.Serialization;
 
 namespace MessageHub.HomeServer.P2p.Libp2p;
 
+public class FindPeersOptions
+{
+    public int? Limit { get; init; }
+    public int? TimeoutSeconds { get; init; }
+    public bool ExcludeSelf { get; init; } = true;
+    public bool RequireAddresses { get; init; } = true;
+}
+
 public sealed class Discovery : IDisposable
 {
     private readonly DiscoveryHandle handle;
@@ -17,7 +26,8 @@ public sealed class Discovery : IDisposable
     {
         ArgumentNullException.ThrowIfNull(dht);
 
-        var handle = NativeMethods.CreateDiscovery(dht.Handle);
+        using var error = NativeMethods.CreateDiscovery(dht.Handle, out var handle);
+        LibP2pException.Check(error);
         return new Discovery(handle);
     }
 
@@ -40,13 +50,18 @@ public sealed class Discovery : IDisposable
         }
     }
 
-    public IEnumerable<(string peerId, string addressInfo)> FindPeers(string topic, CancellationToken cancellationToken = default)
+    public IEnumerable<(string peerId, string addressInfo)> FindPeers(
+        string topic,
+        FindPeersOptions? options = null,
+        CancellationToken cancellationToken = default)
     {
         ArgumentNullException.ThrowIfNull(topic);
 
+        options ??= new FindPeersOptions();
         using var context = new Context(cancellationToken);
         using var topicString = StringHandle.FromString(topic);
-        using var error = NativeMethods.FindPeers(context.Handle, handle, topicString, out var resultHandle);
+        using var optionsJson = StringHandle.FromUtf8Bytes(DefaultJsonSerializer.SerializeToUtf8Bytes(options));
+        using var error = NativeMethods.FindPeers(context.Handle, handle, topicString, optionsJson, out var resultHandle);
         if (!error.IsInvalid)
         {
             cancellationToken.ThrowIfCancellationRequested();
@@ -0,0 +1,37 @@
+using System.Text.Json;
+using MessageHub.HomeServer.P2p.Libp2p.Native;
+
+namespace MessageHub.HomeServer.P2p.Libp2p;
+
+public sealed class EventSubscription : IDisposable
+{
+    private readonly EventSubscriptionHandle handle;
+
+    internal EventSubscription(EventSubscriptionHandle handle)
+    {
+        this.handle = handle;
+    }
+
+    public void Dispose()
+    {
+        handle.Dispose();
+    }
+
+    public void Cancel()
+    {
+        NativeMethods.CancelEventSubscription(handle);
+    }
+
+    public JsonElement Next(CancellationToken cancellationToken = default)
+    {
+        using var context = new Context(cancellationToken);
+        using var error = NativeMethods.GetNextEvent(context.Handle, handle, out var eventJSON);
+        if (!error.IsInvalid)
+        {
+            cancellationToken.ThrowIfCancellationRequested();
+            LibP2pException.Check(error);
+        }
+        using var _ = eventJSON;
+        return JsonSerializer.Deserialize<JsonElement>(eventJSON.ToString());
+    }
+}
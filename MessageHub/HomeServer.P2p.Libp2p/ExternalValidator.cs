@@ -0,0 +1,68 @@
+using System.Text.Json;
+using MessageHub.HomeServer.P2p.Libp2p.Native;
+
+namespace MessageHub.HomeServer.P2p.Libp2p;
+
+public enum ValidationResult
+{
+    Accept,
+    Reject,
+    Ignore
+}
+
+public sealed class ExternalValidator : IDisposable
+{
+    private readonly ValidatorHandle handle;
+
+    internal ValidatorHandle Handle => handle;
+
+    private ExternalValidator(ValidatorHandle handle)
+    {
+        this.handle = handle;
+    }
+
+    public static ExternalValidator Create(TimeSpan timeout)
+    {
+        using var error = NativeMethods.CreateExternalValidator((int)timeout.TotalSeconds, out var validatorHandle);
+        LibP2pException.Check(error);
+        return new ExternalValidator(validatorHandle);
+    }
+
+    public void Dispose()
+    {
+        handle.Dispose();
+    }
+
+    public void Close()
+    {
+        NativeMethods.CloseExternalValidator(handle);
+    }
+
+    public JsonElement Next(CancellationToken cancellationToken = default)
+    {
+        using var context = new Context(cancellationToken);
+        using var error = NativeMethods.GetNextValidation(context.Handle, handle, out var resultJSON);
+        if (!error.IsInvalid)
+        {
+            cancellationToken.ThrowIfCancellationRequested();
+            LibP2pException.Check(error);
+        }
+        using var _ = resultJSON;
+        return JsonSerializer.Deserialize<JsonElement>(resultJSON.ToString());
+    }
+
+    public void SetResult(long id, ValidationResult result)
+    {
+        using var resultString = StringHandle.FromString(result.ToString());
+        using var error = NativeMethods.SetValidationResult(handle, id, resultString);
+        LibP2pException.Check(error);
+    }
+
+    public string GetDropCounters()
+    {
+        using var error = NativeMethods.GetValidationDropCounters(handle, out var resultJSON);
+        LibP2pException.Check(error);
+        using var _ = resultJSON;
+        return resultJSON.ToString();
+    }
+}
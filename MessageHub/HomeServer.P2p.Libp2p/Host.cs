@@ -7,11 +7,80 @@ using MessageHub.Serialization;
 
 namespace MessageHub.HomeServer.P2p.Libp2p;
 
-public class HostConfig
+public class RelayServiceConfig
+{
+    public long? ReservationTTLSeconds { get; init; }
+    public int? MaxReservations { get; init; }
+    public int? MaxCircuits { get; init; }
+    public int? MaxReservationsPerPeer { get; init; }
+    public int? MaxReservationsPerIP { get; init; }
+    public long? CircuitDurationSeconds { get; init; }
+    public long? CircuitDataLimit { get; init; }
+}
+
+public class ConnectionManagerConfig
+{
+    public int? LowWater { get; init; }
+    public int? HighWater { get; init; }
+    public long? GracePeriodSeconds { get; init; }
+}
+
+public class RateLimit
+{
+    public double Rate { get; init; }
+    public int Burst { get; init; }
+}
+
+public class RateLimitSet
+{
+    public RateLimit? PerIP { get; init; }
+    public RateLimit? PerSubnet { get; init; }
+    public RateLimit? PerPeer { get; init; }
+}
+
+public class ConnectionRateLimitConfig
+{
+    public RateLimitSet? Accept { get; init; }
+    public RateLimitSet? Dial { get; init; }
+    public int? SubnetPrefixIPv4 { get; init; }
+    public int? SubnetPrefixIPv6 { get; init; }
+}
+
+public class PrivateNetworkKey
+{
+    public string Secret { get; init; } = default!;
+    public int? Version { get; init; }
+}
+
+public class RendezvousServiceConfig
+{
+    public int? MaxTTLSeconds { get; init; }
+    public int? MaxRegistrations { get; init; }
+    public int? MaxRegistrationsPerPeer { get; init; }
+    public int? MaxDiscoverLimit { get; init; }
+}
+
+public record class HostConfig
 {
     public string[]? StaticRelays { get; init; }
     public string DataPath { get; init; } = default!;
     public string? PrivateNetworkSecret { get; init; }
+    public int? PrivateNetworkKeyVersion { get; init; }
+    public PrivateNetworkKey[]? AcceptedPrivateNetworkSecrets { get; init; }
+    public bool PersistIdentity { get; init; }
+    public string? IdentitySeed { get; init; }
+    public string[]? ListenAddresses { get; init; }
+    public string[]? AnnounceAddresses { get; init; }
+    public string[]? NoAnnounceAddresses { get; init; }
+    public bool EnableWebSocket { get; init; }
+    public RelayServiceConfig? RelayService { get; init; }
+    public bool EnableNATPortMap { get; init; }
+    public ConnectionManagerConfig? ConnectionManager { get; init; }
+    public JsonElement? ResourceLimits { get; init; }
+    public ConnectionRateLimitConfig? ConnectionRateLimits { get; init; }
+    public string? AddressScope { get; init; }
+    public RendezvousServiceConfig? RendezvousService { get; init; }
+    public bool EnableAllowList { get; init; }
 }
 
 public sealed class Proxy : IDisposable
@@ -127,6 +196,189 @@ public sealed class Host : IDisposable
         LibP2pException.Check(error);
     }
 
+    public void Unprotect(string peerId, string tag)
+    {
+        using var peerIdString = StringHandle.FromString(peerId);
+        using var tagString = StringHandle.FromString(tag);
+        using var error = NativeMethods.UnprotectPeer(handle, peerIdString, tagString);
+        LibP2pException.Check(error);
+    }
+
+    public void TagPeer(string peerId, string tag, int value)
+    {
+        using var peerIdString = StringHandle.FromString(peerId);
+        using var tagString = StringHandle.FromString(tag);
+        using var error = NativeMethods.TagPeer(handle, peerIdString, tagString, value);
+        LibP2pException.Check(error);
+    }
+
+    public void UntagPeer(string peerId, string tag)
+    {
+        using var peerIdString = StringHandle.FromString(peerId);
+        using var tagString = StringHandle.FromString(tag);
+        using var error = NativeMethods.UntagPeer(handle, peerIdString, tagString);
+        LibP2pException.Check(error);
+    }
+
+    public string GetPeerTags(string peerId)
+    {
+        using var peerIdString = StringHandle.FromString(peerId);
+        using var error = NativeMethods.GetPeerTags(handle, peerIdString, out var resultJSON);
+        LibP2pException.Check(error);
+        using var _ = resultJSON;
+        return resultJSON.ToString();
+    }
+
+    public void BlockPeer(string peerId)
+    {
+        ArgumentNullException.ThrowIfNull(peerId);
+
+        using var peerIdString = StringHandle.FromString(peerId);
+        using var error = NativeMethods.BlockPeer(handle, peerIdString);
+        LibP2pException.Check(error);
+    }
+
+    public void UnblockPeer(string peerId)
+    {
+        ArgumentNullException.ThrowIfNull(peerId);
+
+        using var peerIdString = StringHandle.FromString(peerId);
+        using var error = NativeMethods.UnblockPeer(handle, peerIdString);
+        LibP2pException.Check(error);
+    }
+
+    public void BlockSubnet(string cidr)
+    {
+        ArgumentNullException.ThrowIfNull(cidr);
+
+        using var cidrString = StringHandle.FromString(cidr);
+        using var error = NativeMethods.BlockSubnet(handle, cidrString);
+        LibP2pException.Check(error);
+    }
+
+    public void UnblockSubnet(string cidr)
+    {
+        ArgumentNullException.ThrowIfNull(cidr);
+
+        using var cidrString = StringHandle.FromString(cidr);
+        using var error = NativeMethods.UnblockSubnet(handle, cidrString);
+        LibP2pException.Check(error);
+    }
+
+    public void AllowPeer(string peerId)
+    {
+        ArgumentNullException.ThrowIfNull(peerId);
+
+        using var peerIdString = StringHandle.FromString(peerId);
+        using var error = NativeMethods.AllowPeer(handle, peerIdString);
+        LibP2pException.Check(error);
+    }
+
+    public void DisallowPeer(string peerId)
+    {
+        ArgumentNullException.ThrowIfNull(peerId);
+
+        using var peerIdString = StringHandle.FromString(peerId);
+        using var error = NativeMethods.DisallowPeer(handle, peerIdString);
+        LibP2pException.Check(error);
+    }
+
+    public void AllowSubnet(string cidr)
+    {
+        ArgumentNullException.ThrowIfNull(cidr);
+
+        using var cidrString = StringHandle.FromString(cidr);
+        using var error = NativeMethods.AllowSubnet(handle, cidrString);
+        LibP2pException.Check(error);
+    }
+
+    public void DisallowSubnet(string cidr)
+    {
+        ArgumentNullException.ThrowIfNull(cidr);
+
+        using var cidrString = StringHandle.FromString(cidr);
+        using var error = NativeMethods.DisallowSubnet(handle, cidrString);
+        LibP2pException.Check(error);
+    }
+
+    public string ListBlocked()
+    {
+        using var error = NativeMethods.ListBlocked(handle, out var resultJSON);
+        LibP2pException.Check(error);
+        using var _ = resultJSON;
+        return resultJSON.ToString();
+    }
+
+    public string ListAllowed()
+    {
+        using var error = NativeMethods.ListAllowed(handle, out var resultJSON);
+        LibP2pException.Check(error);
+        using var _ = resultJSON;
+        return resultJSON.ToString();
+    }
+
+    public string GetRelayServiceStatus()
+    {
+        using var error = NativeMethods.GetRelayServiceStatus(handle, out var resultJSON);
+        LibP2pException.Check(error);
+        using var _ = resultJSON;
+        return resultJSON.ToString();
+    }
+
+    public string GetNetworkStatus()
+    {
+        using var error = NativeMethods.GetNetworkStatus(handle, out var resultJSON);
+        LibP2pException.Check(error);
+        using var _ = resultJSON;
+        return resultJSON.ToString();
+    }
+
+    public EventSubscription SubscribeEvents()
+    {
+        using var error = NativeMethods.CreateEventSubscription(handle, out var subscriptionHandle);
+        LibP2pException.Check(error);
+        return new EventSubscription(subscriptionHandle);
+    }
+
+    public string GetResourceUsage()
+    {
+        using var error = NativeMethods.GetResourceUsage(handle, out var resultJSON);
+        LibP2pException.Check(error);
+        using var _ = resultJSON;
+        return resultJSON.ToString();
+    }
+
+    public string GetRateLimitCounters()
+    {
+        using var error = NativeMethods.GetRateLimitCounters(handle, out var resultJSON);
+        LibP2pException.Check(error);
+        using var _ = resultJSON;
+        return resultJSON.ToString();
+    }
+
+    public string GetPrivateNetworkKeyIds()
+    {
+        using var error = NativeMethods.GetPrivateNetworkKeyIDs(handle, out var resultJSON);
+        LibP2pException.Check(error);
+        using var _ = resultJSON;
+        return resultJSON.ToString();
+    }
+
+    public string? TryGetPeerNetworkKeyId(string peerId)
+    {
+        ArgumentNullException.ThrowIfNull(peerId);
+
+        using var peerIdString = StringHandle.FromString(peerId);
+        using var error = NativeMethods.GetPeerNetworkKeyID(handle, peerIdString, out var keyId);
+        LibP2pException.Check(error);
+        if (keyId.IsInvalid)
+        {
+            return null;
+        }
+        using var _ = keyId;
+        return keyId.ToString();
+    }
+
     public int ConnectToSavedPeers(CancellationToken cancellationToken)
     {
         using var context = new Context(cancellationToken);
@@ -13,8 +13,11 @@ internal sealed class Libp2pNetworkProvider : IDisposable, INetworkProvider
 {
     private readonly ILoggerFactory loggerFactory;
     private readonly ILogger logger;
+    private readonly HostConfig hostConfig;
     private readonly DHTConfig dhtConfig;
-    private readonly Host host;
+    private readonly object locker = new();
+    private string? identitySeed;
+    private Host? host;
     private readonly IMemoryCache memoryCache;
     private readonly PublishEventNotifier publishEventNotifier;
     private readonly IP2pService[] p2pServices;
@@ -56,8 +59,8 @@ internal sealed class Libp2pNetworkProvider : IDisposable, INetworkProvider
 
         this.loggerFactory = loggerFactory;
         logger = loggerFactory.CreateLogger<Libp2pNetworkProvider>();
+        this.hostConfig = hostConfig;
         this.dhtConfig = dhtConfig;
-        host = Host.Create(hostConfig);
         this.memoryCache = memoryCache;
         this.publishEventNotifier = publishEventNotifier;
         p2pServices = new IP2pService[]
@@ -75,19 +78,52 @@ internal sealed class Libp2pNetworkProvider : IDisposable, INetworkProvider
         };
     }
 
+    // The host is created on first use, so that its identity can be derived from the identity seed.
+    private Host GetHost()
+    {
+        lock (locker)
+        {
+            if (host is null)
+            {
+                var config = identitySeed is null ? hostConfig : hostConfig with { IdentitySeed = identitySeed };
+                host = Host.Create(config);
+            }
+            return host;
+        }
+    }
+
+    public void SetIdentitySeed(byte[] seed)
+    {
+        ArgumentNullException.ThrowIfNull(seed);
+
+        string hexSeed = Convert.ToHexString(seed);
+        lock (locker)
+        {
+            if (host is not null && hexSeed != identitySeed)
+            {
+                throw new InvalidOperationException("Host already created with another identity.");
+            }
+            identitySeed = hexSeed;
+        }
+    }
+
     public (KeyIdentifier, string) GetVerifyKey()
     {
-        return (AuthorizedPeer.KeyIdentifier, host.Id);
+        return (AuthorizedPeer.KeyIdentifier, GetHost().Id);
     }
 
     public void Dispose()
     {
-        host.Dispose();
+        lock (locker)
+        {
+            host?.Dispose();
+        }
     }
 
     public void Initialize(Func<ServerKeys, IIdentity?> tryGetIdentity)
     {
         logger.LogInformation("Initializing libp2p...");
+        var host = GetHost();
         logger.LogInformation("Host ID: {}", host.Id);
         if (p2pNode is not null)
         {
@@ -54,6 +54,18 @@ internal sealed class ProxyHandle : ObjectHandle { }
 
 internal sealed class MdnsServiceHandle : ObjectHandle { }
 
+internal sealed class EventSubscriptionHandle : ObjectHandle
+{
+    [DllImport(Native.DllName)]
+    private static extern void CancelEventSubscription(IntPtr handle);
+
+    protected override bool ReleaseHandle()
+    {
+        CancelEventSubscription(handle);
+        return base.ReleaseHandle();
+    }
+}
+
 internal sealed class DHTHandle : ObjectHandle
 {
     [DllImport(Native.DllName)]
@@ -76,6 +88,18 @@ internal sealed class PubSubHandle : ObjectHandle { }
 
 internal sealed class TopicHandle : ObjectHandle { }
 
+internal sealed class ValidatorHandle : ObjectHandle
+{
+    [DllImport(Native.DllName)]
+    private static extern void CloseExternalValidator(IntPtr handle);
+
+    protected override bool ReleaseHandle()
+    {
+        CloseExternalValidator(handle);
+        return base.ReleaseHandle();
+    }
+}
+
 internal sealed class SubscriptionHandle : ObjectHandle { }
 
 internal unsafe static class NativeMethods
@@ -116,6 +140,83 @@ internal unsafe static class NativeMethods
     [DllImport(Native.DllName)]
     public static extern StringHandle ProtectPeer(HostHandle hostHandle, StringHandle peerID, StringHandle tag);
 
+    [DllImport(Native.DllName)]
+    public static extern StringHandle UnprotectPeer(HostHandle hostHandle, StringHandle peerID, StringHandle tag);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle TagPeer(HostHandle hostHandle, StringHandle peerID, StringHandle tag, int value);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle UntagPeer(HostHandle hostHandle, StringHandle peerID, StringHandle tag);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle GetPeerTags(HostHandle hostHandle, StringHandle peerID, out StringHandle resultJSON);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle BlockPeer(HostHandle hostHandle, StringHandle peerID);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle UnblockPeer(HostHandle hostHandle, StringHandle peerID);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle BlockSubnet(HostHandle hostHandle, StringHandle cidr);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle UnblockSubnet(HostHandle hostHandle, StringHandle cidr);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle AllowPeer(HostHandle hostHandle, StringHandle peerID);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle DisallowPeer(HostHandle hostHandle, StringHandle peerID);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle AllowSubnet(HostHandle hostHandle, StringHandle cidr);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle DisallowSubnet(HostHandle hostHandle, StringHandle cidr);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle ListBlocked(HostHandle hostHandle, out StringHandle resultJSON);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle ListAllowed(HostHandle hostHandle, out StringHandle resultJSON);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle GetRelayServiceStatus(HostHandle hostHandle, out StringHandle resultJSON);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle GetNetworkStatus(HostHandle hostHandle, out StringHandle resultJSON);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle GetResourceUsage(HostHandle hostHandle, out StringHandle resultJSON);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle GetRateLimitCounters(HostHandle hostHandle, out StringHandle resultJSON);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle GetPrivateNetworkKeyIDs(HostHandle hostHandle, out StringHandle resultJSON);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle GetPeerNetworkKeyID(
+        HostHandle hostHandle,
+        StringHandle peerID,
+        out StringHandle keyID);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle CreateEventSubscription(
+        HostHandle hostHandle,
+        out EventSubscriptionHandle subscriptionHandle);
+
+    [DllImport(Native.DllName)]
+    public static extern void CancelEventSubscription(EventSubscriptionHandle subscriptionHandle);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle GetNextEvent(
+        ContextHandle ctxHandle,
+        EventSubscriptionHandle subscriptionHandle,
+        out StringHandle eventJSON);
+
     [DllImport(Native.DllName)]
     public static extern StringHandle ConnectToSavedPeers(ContextHandle ctxHandle, HostHandle hostHandle);
 
@@ -170,7 +271,7 @@ internal unsafe static class NativeMethods
         DHTHandle dhtHandle);
 
     [DllImport(Native.DllName)]
-    public static extern DiscoveryHandle CreateDiscovery(DHTHandle dhtHandle);
+    public static extern StringHandle CreateDiscovery(DHTHandle dhtHandle, out DiscoveryHandle result);
 
     [DllImport(Native.DllName)]
     public static extern StringHandle Advertise(
@@ -184,6 +285,7 @@ internal unsafe static class NativeMethods
         ContextHandle ctxHandle,
         DiscoveryHandle discoveryHandle,
         StringHandle topic,
+        StringHandle optionsJSON,
         out PeerChanHandle result);
 
 
@@ -219,8 +321,38 @@ internal unsafe static class NativeMethods
         ContextHandle ctxHandle,
         DHTHandle dhtHandle,
         MemberStoreHandle memberStoreHandle,
+        DiscoveryHandle discoveryHandle,
+        StringHandle configJSON,
         out PubSubHandle pubsubHandle);
 
+    [DllImport(Native.DllName)]
+    public static extern StringHandle RegisterTopicValidator(
+        PubSubHandle pubsubHandle,
+        StringHandle topic,
+        StringHandle validator,
+        ValidatorHandle externalValidatorHandle);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle UnregisterTopicValidator(PubSubHandle pubsubHandle, StringHandle topic);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle CreateExternalValidator(int timeoutSeconds, out ValidatorHandle validatorHandle);
+
+    [DllImport(Native.DllName)]
+    public static extern void CloseExternalValidator(ValidatorHandle validatorHandle);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle GetNextValidation(
+        ContextHandle ctxHandle,
+        ValidatorHandle validatorHandle,
+        out StringHandle resultJSON);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle GetValidationDropCounters(ValidatorHandle validatorHandle, out StringHandle resultJSON);
+
+    [DllImport(Native.DllName)]
+    public static extern StringHandle SetValidationResult(ValidatorHandle validatorHandle, long id, StringHandle result);
+
     [DllImport(Native.DllName)]
     public static extern StringHandle JoinTopic(
         PubSubHandle pubsubHandle,
@@ -144,7 +144,7 @@ internal class P2pNode : IDisposable
             try
             {
                 logger.LogDebug("Finding peers for topic {}...", topic);
-                var addressInfos = Discovery.FindPeers(topic, token);
+                var addressInfos = Discovery.FindPeers(topic, cancellationToken: token);
                 var parallelOptions = new ParallelOptions
                 {
                     CancellationToken = token,
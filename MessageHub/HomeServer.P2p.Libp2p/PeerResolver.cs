@@ -80,7 +80,7 @@ public class PeerResolver : IPeerResolver
                         {
                             yield return cachedInfo.Value;
                         }
-                        var peers = discovery.FindPeers(rendezvousPoint, token);
+                        var peers = discovery.FindPeers(rendezvousPoint, cancellationToken: token);
                         foreach (var (id, info) in peers)
                         {
                             if ((id, info).Equals(cachedInfo))
@@ -1,7 +1,15 @@
+using System.Text.Json;
 using MessageHub.HomeServer.P2p.Libp2p.Native;
+using MessageHub.Serialization;
 
 namespace MessageHub.HomeServer.P2p.Libp2p;
 
+public class PubSubConfig
+{
+    public string? MessageIDFunction { get; init; }
+    public JsonElement? PeerScore { get; init; }
+}
+
 public sealed class PubSub : IDisposable
 {
     private readonly PubSubHandle handle;
@@ -13,16 +21,25 @@ public sealed class PubSub : IDisposable
         this.handle = handle;
     }
 
-    public static PubSub Create(DHT dht, MemberStore memberStore, CancellationToken cancellationToken = default)
+    public static PubSub Create(
+        DHT dht,
+        MemberStore memberStore,
+        PubSubConfig? config = null,
+        Discovery? discovery = null,
+        CancellationToken cancellationToken = default)
     {
         ArgumentNullException.ThrowIfNull(dht);
         ArgumentNullException.ThrowIfNull(memberStore);
 
+        config ??= new PubSubConfig();
         using var context = new Context(cancellationToken);
+        using var configJson = StringHandle.FromUtf8Bytes(DefaultJsonSerializer.SerializeToUtf8Bytes(config));
         using var error = NativeMethods.CreatePubSub(
             context.Handle,
             dht.Handle,
             memberStore.Handle,
+            discovery?.Handle ?? new DiscoveryHandle(),
+            configJson,
             out var pubsubHandle);
         if (!error.IsInvalid)
         {
@@ -37,6 +54,32 @@ public sealed class PubSub : IDisposable
         handle.Dispose();
     }
 
+    public void RegisterTopicValidator(
+        string topic,
+        string? validator = null,
+        ExternalValidator? externalValidator = null)
+    {
+        ArgumentNullException.ThrowIfNull(topic);
+
+        using var topicString = StringHandle.FromString(topic);
+        using var validatorString = validator is null ? new StringHandle() : StringHandle.FromString(validator);
+        using var error = NativeMethods.RegisterTopicValidator(
+            handle,
+            topicString,
+            validatorString,
+            externalValidator?.Handle ?? new ValidatorHandle());
+        LibP2pException.Check(error);
+    }
+
+    public void UnregisterTopicValidator(string topic)
+    {
+        ArgumentNullException.ThrowIfNull(topic);
+
+        using var topicString = StringHandle.FromString(topic);
+        using var error = NativeMethods.UnregisterTopicValidator(handle, topicString);
+        LibP2pException.Check(error);
+    }
+
     public Topic JoinTopic(string topic)
     {
         ArgumentNullException.ThrowIfNull(topic);
@@ -31,7 +31,7 @@ internal class Advertiser
                 using var cts = CancellationTokenSource.CreateLinkedTokenSource(cancellationToken);
                 cts.CancelAfter(TimeSpan.FromSeconds(20));
                 string selfId = p2pNode.Host.Id;
-                var peers = p2pNode.Discovery.FindPeers(topic, cts.Token);
+                var peers = p2pNode.Discovery.FindPeers(topic, cancellationToken: cts.Token);
                 int count = 0;
                 foreach (var (peerId, _) in peers)
                 {
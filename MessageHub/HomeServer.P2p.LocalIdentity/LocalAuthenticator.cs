@@ -68,6 +68,8 @@ public sealed class LocalAuthenticator : IAuthenticator, IDisposable
             }
             try
             {
+                // Keeps the peer ID of the network provider stable across restarts.
+                networkProvider.SetIdentitySeed(privateKeyBlob);
                 return (created, Key.Import(SignatureAlgorithm.Ed25519, privateKeyBlob, KeyBlobFormat.RawPrivateKey));
             }
             finally
@@ -1,4 +1,6 @@
+using System.Text.Json;
 using System.Text.Json.Serialization;
+using MessageHub.HomeServer.P2p.Libp2p;
 
 namespace MessageHub.HomeServer.P2p;
 
@@ -13,9 +15,57 @@ public class Config
     [JsonPropertyName("libp2p.privateNetworkSecret")]
     public string? PrivateNetworkSecret { get; set; }
 
+    [JsonPropertyName("libp2p.privateNetworkKeyVersion")]
+    public int? PrivateNetworkKeyVersion { get; set; }
+
+    [JsonPropertyName("libp2p.acceptedPrivateNetworkSecrets")]
+    public PrivateNetworkKey[]? AcceptedPrivateNetworkSecrets { get; set; }
+
+    [JsonPropertyName("libp2p.listenAddresses")]
+    public string[]? ListenAddresses { get; set; }
+
+    [JsonPropertyName("libp2p.announceAddresses")]
+    public string[]? AnnounceAddresses { get; set; }
+
+    [JsonPropertyName("libp2p.noAnnounceAddresses")]
+    public string[]? NoAnnounceAddresses { get; set; }
+
+    [JsonPropertyName("libp2p.enableWebSocket")]
+    public bool EnableWebSocket { get; set; }
+
+    [JsonPropertyName("libp2p.relayService")]
+    public RelayServiceConfig? RelayService { get; set; }
+
+    [JsonPropertyName("libp2p.enableNATPortMap")]
+    public bool EnableNATPortMap { get; set; }
+
+    [JsonPropertyName("libp2p.connectionManager")]
+    public ConnectionManagerConfig? ConnectionManager { get; set; }
+
+    [JsonPropertyName("libp2p.resourceLimits")]
+    public JsonElement? ResourceLimits { get; set; }
+
+    [JsonPropertyName("libp2p.connectionRateLimits")]
+    public ConnectionRateLimitConfig? ConnectionRateLimits { get; set; }
+
+    [JsonPropertyName("libp2p.addressScope")]
+    public string? AddressScope { get; set; }
+
+    [JsonPropertyName("libp2p.rendezvousService")]
+    public RendezvousServiceConfig? RendezvousService { get; set; }
+
+    [JsonPropertyName("libp2p.enableAllowList")]
+    public bool EnableAllowList { get; set; }
+
     [JsonPropertyName("libp2p.dht.bootstrapPeers")]
     public string[]? BootstrapPeers { get; set; }
 
+    [JsonPropertyName("libp2p.dht.mode")]
+    public string? DHTMode { get; set; }
+
+    [JsonPropertyName("libp2p.dht.protocolPrefix")]
+    public string? DHTProtocolPrefix { get; set; }
+
     [JsonPropertyName("fasterKV.pageSize")]
     public long? FasterKVPageSize { get; set; }
 
@@ -5,6 +5,7 @@ namespace MessageHub.HomeServer.P2p.Providers;
 
 public interface INetworkProvider
 {
+    void SetIdentitySeed(byte[] seed);
     (KeyIdentifier, string) GetVerifyKey();
     void Initialize(Func<ServerKeys, IIdentity?> tryGetIdentity);
     void Shutdown();
@@ -1,6 +1,6 @@
+using System.Globalization;
 using System.Numerics;
 using System.Text;
-using System.Text.Encodings.Web;
 using System.Text.Json;
 using MessageHub.Serialization;
 
@@ -13,39 +13,98 @@ public static class CanonicalJson
     private static readonly BigInteger minNumber = -BigInteger.Pow(2, 53) + 1;
     private static readonly BigInteger maxNumber = BigInteger.Pow(2, 53) - 1;
 
-    private class CanonicalJsonWriter : IDisposable
+    // Matrix canonical JSON: keys sorted by code point, and only quotes, backslashes
+    // and control characters escaped in strings, so that other implementations produce the same bytes.
+    private class CanonicalJsonWriter
     {
-        private readonly MemoryStream stream;
-        private readonly Utf8JsonWriter writer;
+        private readonly StringBuilder builder = new();
 
-        public CanonicalJsonWriter()
+        private static int CompareCodePoints(string x, string y)
         {
-            stream = new MemoryStream();
-            writer = new Utf8JsonWriter(stream, new JsonWriterOptions
+            // Byte order of UTF-8 strings is code point order.
+            var xBytes = new ReadOnlySpan<byte>(Encoding.UTF8.GetBytes(x));
+            var yBytes = new ReadOnlySpan<byte>(Encoding.UTF8.GetBytes(y));
+            return xBytes.SequenceCompareTo(yBytes);
+        }
+
+        private void WriteString(string value)
+        {
+            builder.Append('"');
+            foreach (char c in value)
             {
-                Encoder = JavaScriptEncoder.UnsafeRelaxedJsonEscaping
-            });
+                switch (c)
+                {
+                    case '"':
+                        builder.Append("\\\"");
+                        break;
+                    case '\\':
+                        builder.Append("\\\\");
+                        break;
+                    case '\b':
+                        builder.Append("\\b");
+                        break;
+                    case '\f':
+                        builder.Append("\\f");
+                        break;
+                    case '\n':
+                        builder.Append("\\n");
+                        break;
+                    case '\r':
+                        builder.Append("\\r");
+                        break;
+                    case '\t':
+                        builder.Append("\\t");
+                        break;
+                    default:
+                        if (c < 0x20)
+                        {
+                            builder.Append("\\u");
+                            builder.Append(((int)c).ToString("x4", CultureInfo.InvariantCulture));
+                        }
+                        else
+                        {
+                            builder.Append(c);
+                        }
+                        break;
+                }
+            }
+            builder.Append('"');
         }
 
-        private void WriteObject(JsonElement element, Action<JsonElement> writeElement)
+        private void WriteObject(JsonElement element)
         {
-            writer.WriteStartObject();
-            foreach (var property in element.EnumerateObject().OrderBy(property => property.Name))
+            builder.Append('{');
+            bool isFirst = true;
+            var properties = element.EnumerateObject().ToArray();
+            Array.Sort(properties, (x, y) => CompareCodePoints(x.Name, y.Name));
+            foreach (var property in properties)
             {
-                writer.WritePropertyName(property.Name);
-                writeElement(property.Value);
+                if (!isFirst)
+                {
+                    builder.Append(',');
+                }
+                isFirst = false;
+                WriteString(property.Name);
+                builder.Append(':');
+                WriteElement(property.Value);
             }
-            writer.WriteEndObject();
+            builder.Append('}');
         }
 
-        private void WriteArray(JsonElement element, Action<JsonElement> writeElement)
+        private void WriteArray(JsonElement element)
         {
-            writer.WriteStartArray();
+            builder.Append('[');
+            bool isFirst = true;
             foreach (var item in element.EnumerateArray())
             {
-                writeElement(item);
+                if (!isFirst)
+                {
+                    builder.Append(',');
+                }
+                isFirst = false;
+                WriteElement(item);
             }
-            writer.WriteEndArray();
+            builder.Append(']');
         }
 
         private void WriteNumber(JsonElement element)
@@ -55,47 +114,49 @@ public static class CanonicalJson
                 var operation = new CheckNumberRangeOperation(element);
                 throw new InvalidOperationException(operation.ToString());
             }
-            writer.WriteNumberValue(value);
+            builder.Append(value.ToString(CultureInfo.InvariantCulture));
         }
 
         private void WriteElement(JsonElement element)
         {
-            if (element.ValueKind == JsonValueKind.Object)
+            switch (element.ValueKind)
             {
-                WriteObject(element, WriteElement);
+                case JsonValueKind.Object:
+                    WriteObject(element);
+                    break;
+                case JsonValueKind.Array:
+                    WriteArray(element);
+                    break;
+                case JsonValueKind.Number:
+                    WriteNumber(element);
+                    break;
+                case JsonValueKind.String:
+                    WriteString(element.GetString()!);
+                    break;
+                case JsonValueKind.True:
+                    builder.Append("true");
+                    break;
+                case JsonValueKind.False:
+                    builder.Append("false");
+                    break;
+                case JsonValueKind.Null:
+                    builder.Append("null");
+                    break;
+                default:
+                    throw new InvalidOperationException(element.ValueKind.ToString());
             }
-            else if (element.ValueKind == JsonValueKind.Array)
-            {
-                WriteArray(element, WriteElement);
-            }
-            else if (element.ValueKind == JsonValueKind.Number)
-            {
-                WriteNumber(element);
-            }
-            else
-            {
-                element.WriteTo(writer);
-            }
-            writer.Flush();
-        }
-
-        public void Dispose()
-        {
-            writer.Dispose();
-            stream.Dispose();
         }
 
-        public static byte[] WriteBytes(JsonElement element)
+        public static string WriteJson(JsonElement element)
         {
-            using var writer = new CanonicalJsonWriter();
+            var writer = new CanonicalJsonWriter();
             writer.WriteElement(element);
-            return writer.stream.ToArray();
+            return writer.builder.ToString();
         }
 
-        public static string WriteJson(JsonElement element)
+        public static byte[] WriteBytes(JsonElement element)
         {
-            var bytes = WriteBytes(element);
-            return Encoding.UTF8.GetString(bytes);
+            return Encoding.UTF8.GetBytes(WriteJson(element));
         }
     }
 
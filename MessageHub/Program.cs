@@ -52,11 +52,27 @@ public class Program
             {
                 StaticRelays = config.StaticRelays,
                 DataPath = config.DataPath,
-                PrivateNetworkSecret = config.PrivateNetworkSecret
+                PrivateNetworkSecret = config.PrivateNetworkSecret,
+                PrivateNetworkKeyVersion = config.PrivateNetworkKeyVersion,
+                AcceptedPrivateNetworkSecrets = config.AcceptedPrivateNetworkSecrets,
+                ListenAddresses = config.ListenAddresses,
+                AnnounceAddresses = config.AnnounceAddresses,
+                NoAnnounceAddresses = config.NoAnnounceAddresses,
+                EnableWebSocket = config.EnableWebSocket,
+                RelayService = config.RelayService,
+                EnableNATPortMap = config.EnableNATPortMap,
+                ConnectionManager = config.ConnectionManager,
+                ResourceLimits = config.ResourceLimits,
+                ConnectionRateLimits = config.ConnectionRateLimits,
+                AddressScope = config.AddressScope,
+                RendezvousService = config.RendezvousService,
+                EnableAllowList = config.EnableAllowList
             },
             new DHTConfig
             {
-                BootstrapPeers = config.BootstrapPeers
+                BootstrapPeers = config.BootstrapPeers,
+                Mode = config.DHTMode,
+                ProtocolPrefix = config.DHTProtocolPrefix
             });
         builder.Services.AddLocalIdentity();
         builder.Services.AddP2pHomeServer();
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/config"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// Builds the AddrsFactory for the host.
+// Announce addresses replace the listen addresses if specified,
+// no-announce entries are either CIDR ranges, in plain or "/ip4/<ip>/ipcidr/<prefix>" form, or exact multiaddresses.
+func createAddrsFactory(announce []string, noAnnounce []string) (config.AddrsFactory, error) {
+	announceAddrs := make([]ma.Multiaddr, 0, len(announce))
+	for _, s := range announce {
+		addr, err := ma.NewMultiaddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing announce address: %w", err)
+		}
+		announceAddrs = append(announceAddrs, addr)
+	}
+	filters := ma.NewFilters()
+	excludedAddrs := make(map[string]struct{})
+	for _, s := range noAnnounce {
+		if _, ipNet, err := net.ParseCIDR(s); err == nil {
+			filters.AddFilter(*ipNet, ma.ActionDeny)
+			continue
+		}
+		if ipNet, ok, err := parseIPCIDR(s); ok {
+			if err != nil {
+				return nil, fmt.Errorf("error parsing no-announce address: %w", err)
+			}
+			filters.AddFilter(*ipNet, ma.ActionDeny)
+			continue
+		}
+		addr, err := ma.NewMultiaddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing no-announce address: %w", err)
+		}
+		excludedAddrs[string(addr.Bytes())] = struct{}{}
+	}
+
+	return func(addrs []ma.Multiaddr) []ma.Multiaddr {
+		if len(announceAddrs) > 0 {
+			addrs = announceAddrs
+		}
+		result := make([]ma.Multiaddr, 0, len(addrs))
+		for _, addr := range addrs {
+			if _, ok := excludedAddrs[string(addr.Bytes())]; ok {
+				continue
+			}
+			if filters.AddrBlocked(addr) {
+				continue
+			}
+			result = append(result, addr)
+		}
+		return result
+	}, nil
+}
+
+// Parses the multiaddress form of CIDR ranges, which this version of go-multiaddr has no protocol for.
+// Returns false if s is not in that form.
+func parseIPCIDR(s string) (*net.IPNet, bool, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 5 || parts[0] != "" || parts[3] != "ipcidr" {
+		return nil, false, nil
+	}
+	ip := net.ParseIP(parts[2])
+	switch {
+	case parts[1] == "ip4" && ip != nil && ip.To4() != nil:
+	case parts[1] == "ip6" && ip != nil && ip.To4() == nil:
+	default:
+		return nil, true, fmt.Errorf("invalid IP address: %s", s)
+	}
+	_, ipNet, err := net.ParseCIDR(parts[2] + "/" + parts[4])
+	if err != nil {
+		return nil, true, err
+	}
+	return ipNet, true, nil
+}
+
+func uniqueAddrs(addrs []ma.Multiaddr) []ma.Multiaddr {
+	seen := make(map[string]struct{}, len(addrs))
+	result := make([]ma.Multiaddr, 0, len(addrs))
+	for _, addr := range addrs {
+		key := string(addr.Bytes())
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		result = append(result, addr)
+	}
+	return result
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestAddrsFactoryNoAnnounce(t *testing.T) {
+	addrsFactory, err := createAddrsFactory(nil, []string{
+		"10.0.0.0/8",
+		"/ip4/192.168.0.0/ipcidr/16",
+		"/ip6/fd00::/ipcidr/8",
+		"/ip4/127.0.0.1/tcp/4001",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs := []ma.Multiaddr{
+		ma.StringCast("/ip4/10.1.2.3/tcp/4001"),
+		ma.StringCast("/ip4/192.168.1.1/udp/4001/quic"),
+		ma.StringCast("/ip6/fd12::1/tcp/4001"),
+		ma.StringCast("/ip4/127.0.0.1/tcp/4001"),
+		ma.StringCast("/ip4/127.0.0.1/tcp/4002"),
+		ma.StringCast("/ip4/1.2.3.4/tcp/4001"),
+	}
+	result := addrsFactory(addrs)
+	expected := []string{"/ip4/127.0.0.1/tcp/4002", "/ip4/1.2.3.4/tcp/4001"}
+	if len(result) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+	for i, addr := range result {
+		if addr.String() != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, result)
+		}
+	}
+}
+
+func TestAddrsFactoryRejectsInvalidNoAnnounce(t *testing.T) {
+	for _, s := range []string{"/ip4/10.0.0.0/ipcidr/33", "/ip4/fd00::/ipcidr/8", "/ip4/10.0.0.0/ipcidr/x", "invalid"} {
+		if _, err := createAddrsFactory(nil, []string{s}); err == nil {
+			t.Fatalf("expected %s to be rejected", s)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/discovery"
+	"github.com/libp2p/go-libp2p-core/network"
+)
+
+const (
+	// Guards against busy refreshing when discovery grants a zero or very short TTL.
+	minAdvertiseInterval = time.Minute
+	minAdvertiseBackoff  = 10 * time.Second
+	maxAdvertiseBackoff  = 5 * time.Minute
+)
+
+type advertiseStatus struct {
+	// Unix milliseconds, 0 if never.
+	LastSuccess int64
+	LastError   string `json:",omitempty"`
+	NextAttempt int64
+}
+
+type advertisedTopic struct {
+	cancel context.CancelFunc
+	status advertiseStatus
+}
+
+// Keeps advertisements of rendezvous topics alive in the background.
+type advertiser struct {
+	advertiser discovery.Advertiser
+	ctx        context.Context
+	cancel     context.CancelFunc
+	mutex      sync.Mutex
+	ttl        time.Duration
+	topics     map[string]*advertisedTopic
+}
+
+func newAdvertiser(d discovery.Advertiser, topics []string, ttl time.Duration) *advertiser {
+	ctx := network.WithUseTransient(context.Background(), "")
+	ctx, cancel := context.WithCancel(ctx)
+	a := &advertiser{
+		advertiser: d,
+		ctx:        ctx,
+		cancel:     cancel,
+		topics:     make(map[string]*advertisedTopic),
+	}
+	a.update(topics, ttl)
+	return a
+}
+
+// Starts advertising new topics and stops removed ones, the TTL applies from the next refresh.
+func (a *advertiser) update(topics []string, ttl time.Duration) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.ttl = ttl
+	keep := make(map[string]struct{}, len(topics))
+	for _, topic := range topics {
+		keep[topic] = struct{}{}
+		if _, ok := a.topics[topic]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(a.ctx)
+		a.topics[topic] = &advertisedTopic{cancel: cancel}
+		go a.run(ctx, topic)
+	}
+	for topic, t := range a.topics {
+		if _, ok := keep[topic]; !ok {
+			t.cancel()
+			delete(a.topics, topic)
+		}
+	}
+}
+
+func (a *advertiser) getTTL() time.Duration {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.ttl
+}
+
+func (a *advertiser) setStatus(topic string, update func(status *advertiseStatus)) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if t, ok := a.topics[topic]; ok {
+		update(&t.status)
+	}
+}
+
+func (a *advertiser) run(ctx context.Context, topic string) {
+	backoff := minAdvertiseBackoff
+	for {
+		var wait time.Duration
+		ttl, err := a.advertiser.Advertise(ctx, topic, discovery.TTL(a.getTTL()))
+		if ctx.Err() != nil {
+			return
+		}
+		now := time.Now()
+		if err == nil {
+			backoff = minAdvertiseBackoff
+			// Refresh before the advertisement expires.
+			wait = ttl * 7 / 8
+			if wait < minAdvertiseInterval {
+				wait = minAdvertiseInterval
+			}
+			a.setStatus(topic, func(status *advertiseStatus) {
+				status.LastSuccess = now.UnixMilli()
+				status.LastError = ""
+				status.NextAttempt = now.Add(wait).UnixMilli()
+			})
+		} else {
+			wait = backoff + time.Duration(rand.Int63n(int64(backoff/2)))
+			backoff *= 2
+			if backoff > maxAdvertiseBackoff {
+				backoff = maxAdvertiseBackoff
+			}
+			a.setStatus(topic, func(status *advertiseStatus) {
+				status.LastError = err.Error()
+				status.NextAttempt = now.Add(wait).UnixMilli()
+			})
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (a *advertiser) getStatus() map[string]advertiseStatus {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	result := make(map[string]advertiseStatus, len(a.topics))
+	for topic, t := range a.topics {
+		result[topic] = t.status
+	}
+	return result
+}
+
+func (a *advertiser) stop() {
+	a.cancel()
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.topics = make(map[string]*advertisedTopic)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Same range as the Matrix canonical JSON.
+const maxCanonicalInteger = 1<<53 - 1
+
+func checkCanonicalNumbers(value any) error {
+	switch v := value.(type) {
+	case map[string]any:
+		for _, item := range v {
+			if err := checkCanonicalNumbers(item); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := checkCanonicalNumbers(item); err != nil {
+				return err
+			}
+		}
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil || n > maxCanonicalInteger || n < -maxCanonicalInteger {
+			return fmt.Errorf("invalid number in canonical JSON: %s", v)
+		}
+	case float64:
+		if v != math.Trunc(v) || v > maxCanonicalInteger || v < -maxCanonicalInteger {
+			return fmt.Errorf("invalid number in canonical JSON: %v", v)
+		}
+	}
+	return nil
+}
+
+// Encodes a value decoded with json.Decoder.UseNumber as Matrix canonical JSON, the same bytes as CanonicalJson:
+// keys sorted by code point, no insignificant whitespace, integers only and UTF-8 strings
+// with only quotes, backslashes and control characters escaped.
+func encodeCanonicalJSON(value any) ([]byte, error) {
+	if err := checkCanonicalNumbers(value); err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	err := writeCanonicalJSON(&buffer, value)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func writeCanonicalJSON(buffer *bytes.Buffer, value any) error {
+	switch v := value.(type) {
+	case nil:
+		buffer.WriteString("null")
+	case bool:
+		buffer.WriteString(strconv.FormatBool(v))
+	case string:
+		writeCanonicalString(buffer, v)
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return err
+		}
+		buffer.WriteString(strconv.FormatInt(n, 10))
+	case float64:
+		buffer.WriteString(strconv.FormatInt(int64(v), 10))
+	case map[string]any:
+		// Byte order of UTF-8 keys is code point order.
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buffer.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			writeCanonicalString(buffer, key)
+			buffer.WriteByte(':')
+			if err := writeCanonicalJSON(buffer, v[key]); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte('}')
+	case []any:
+		buffer.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			if err := writeCanonicalJSON(buffer, item); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte(']')
+	default:
+		// Other Go values are encoded through their JSON representation.
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		decoded, err := decodeJSON(data)
+		if err != nil {
+			return err
+		}
+		if err := checkCanonicalNumbers(decoded); err != nil {
+			return err
+		}
+		return writeCanonicalJSON(buffer, decoded)
+	}
+	return nil
+}
+
+func writeCanonicalString(buffer *bytes.Buffer, s string) {
+	buffer.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buffer.WriteString(`\"`)
+		case '\\':
+			buffer.WriteString(`\\`)
+		case '\b':
+			buffer.WriteString(`\b`)
+		case '\f':
+			buffer.WriteString(`\f`)
+		case '\n':
+			buffer.WriteString(`\n`)
+		case '\r':
+			buffer.WriteString(`\r`)
+		case '\t':
+			buffer.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buffer, `\u%04x`, r)
+			} else {
+				buffer.WriteRune(r)
+			}
+		}
+	}
+	buffer.WriteByte('"')
+}
+
+func decodeJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
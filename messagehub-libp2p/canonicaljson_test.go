@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+)
+
+// Pairs of JSON values and their canonical encoding, shared with the CanonicalJson tests.
+const canonicalJSONExamplesPath = "../MessageHub.Tests/HomeServer/json-examples.txt"
+
+func TestEncodeCanonicalJSON(t *testing.T) {
+	data, err := os.ReadFile(canonicalJSONExamplesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var examples []string
+	for {
+		var value json.RawMessage
+		err := decoder.Decode(&value)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		examples = append(examples, string(value))
+	}
+	if len(examples) == 0 || len(examples)%2 != 0 {
+		t.Fatalf("unexpected number of examples: %d", len(examples))
+	}
+	for i := 0; i < len(examples); i += 2 {
+		original, expected := examples[i], examples[i+1]
+		decoded, err := decodeJSON([]byte(original))
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := encodeCanonicalJSON(decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(actual) != expected {
+			t.Fatalf("expected %s, got %s", expected, actual)
+		}
+	}
+}
+
+func TestEncodeCanonicalJSONRejectsInvalidNumbers(t *testing.T) {
+	for _, original := range []string{`{"a":1.5}`, `{"a":9007199254740992}`, `[-9007199254740992]`} {
+		decoded, err := decodeJSON([]byte(original))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := encodeCanonicalJSON(decoded); err == nil {
+			t.Fatalf("expected %s to be rejected", original)
+		}
+	}
+}
@@ -1,11 +1,37 @@
 package main
 
+import "encoding/json"
+
 type HostConfig struct {
-	StaticRelays         *[]string
-	DataPath             string
-	PrivateNetworkSecret *string
+	StaticRelays                  *[]string
+	DataPath                      string
+	PrivateNetworkSecret          *string
+	PrivateNetworkKeyVersion      *int
+	AcceptedPrivateNetworkSecrets *[]PrivateNetworkKey
+	AddressScope                  *string
+	PersistIdentity               bool
+	IdentitySeed                  *string
+	ListenAddresses               *[]string
+	AnnounceAddresses             *[]string
+	NoAnnounceAddresses           *[]string
+	EnableWebSocket               bool
+	RelayService                  *RelayServiceConfig
+	RendezvousService             *RendezvousServiceConfig
+	EnableNATPortMap              bool
+	ConnectionManager             *ConnectionManagerConfig
+	ResourceLimits                *json.RawMessage
+	ConnectionRateLimits          *ConnectionRateLimitConfig
+	EnableAllowList               bool
 }
 
 type DHTConfig struct {
 	BootstrapPeers *[]string
+	Mode           *string
+	// Under the default "/ipfs" prefix, server keys records are stored in a separate "/messagehub" DHT.
+	ProtocolPrefix *string
+}
+
+type PubSubConfig struct {
+	MessageIDFunction *string
+	PeerScore         *PeerScoreConfig
 }
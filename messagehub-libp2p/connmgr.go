@@ -0,0 +1,51 @@
+package main
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p/p2p/net/connmgr"
+)
+
+type ConnectionManagerConfig struct {
+	LowWater           *int
+	HighWater          *int
+	GracePeriodSeconds *int64
+}
+
+type peerTags struct {
+	Value     int
+	Tags      map[string]int
+	Protected bool
+}
+
+func createConnManager(config ConnectionManagerConfig) (*connmgr.BasicConnMgr, error) {
+	// Same watermarks as libp2p.DefaultConnectionManager.
+	low, high := 160, 192
+	if config.LowWater != nil {
+		low = *config.LowWater
+	}
+	if config.HighWater != nil {
+		high = *config.HighWater
+	}
+	options := make([]connmgr.Option, 0)
+	if config.GracePeriodSeconds != nil {
+		options = append(options, connmgr.WithGracePeriod(time.Duration(*config.GracePeriodSeconds)*time.Second))
+	}
+	return connmgr.NewConnManager(low, high, options...)
+}
+
+func getPeerTags(host host.Host, peerID peer.ID) peerTags {
+	result := peerTags{
+		Tags:      make(map[string]int),
+		Protected: host.ConnManager().IsProtected(peerID, ""),
+	}
+	if tagInfo := host.ConnManager().GetTagInfo(peerID); tagInfo != nil {
+		result.Value = tagInfo.Value
+		for tag, value := range tagInfo.Tags {
+			result.Tags[tag] = value
+		}
+	}
+	return result
+}
@@ -6,16 +6,60 @@ import (
 
 	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
 	dht "github.com/libp2p/go-libp2p-kad-dht"
 	"github.com/libp2p/go-libp2p-kad-dht/dual"
+	record "github.com/libp2p/go-libp2p-record"
 )
 
-func createDHT(ctx context.Context, host host.Host, config DHTConfig) (*dual.DHT, error) {
+// Private networks build their own DHT instead of joining the IPFS one,
+// public networks use it to store server keys records.
+const messageHubDHTPrefix protocol.ID = "/messagehub"
+
+// Server keys records are stored in a separate DHT when the main one is the IPFS DHT,
+// as kad-dht refuses custom validators under the default "/ipfs" prefix.
+type DHTNode struct {
+	dht     *dual.DHT
+	records *dual.DHT
+}
+
+func parseDHTMode(mode string) (dht.ModeOpt, error) {
+	switch mode {
+	case "auto":
+		return dht.ModeAuto, nil
+	case "auto-server":
+		return dht.ModeAutoServer, nil
+	case "client":
+		return dht.ModeClient, nil
+	case "server":
+		return dht.ModeServer, nil
+	}
+	return 0, fmt.Errorf("invalid DHT mode: %s", mode)
+}
+
+func createDHT(ctx context.Context, host host.Host, config DHTConfig, scope AddressScope, isPrivateNetwork bool) (*DHTNode, error) {
 	options := make([]dht.Option, 0)
+	if config.Mode != nil {
+		mode, err := parseDHTMode(*config.Mode)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, dht.Mode(mode))
+	}
+	prefix := dht.DefaultPrefix
+	if config.ProtocolPrefix != nil {
+		prefix = protocol.ID(*config.ProtocolPrefix)
+	} else if isPrivateNetwork {
+		prefix = messageHubDHTPrefix
+	}
+	var bootstrapPeers []peer.AddrInfo
 	if config.BootstrapPeers == nil {
-		options = append(options, dht.BootstrapPeers(dht.GetDefaultBootstrapPeerAddrInfos()...))
+		// The default bootstrap peers never share the secret of a private network.
+		if !isPrivateNetwork {
+			bootstrapPeers = dht.GetDefaultBootstrapPeerAddrInfos()
+		}
 	} else {
-		bootstrapPeers := make([]peer.AddrInfo, len(*config.BootstrapPeers))
+		bootstrapPeers = make([]peer.AddrInfo, 0, len(*config.BootstrapPeers))
 		for _, s := range *config.BootstrapPeers {
 			addrInfo, err := peer.AddrInfoFromString(s)
 			if err != nil {
@@ -23,8 +67,73 @@ func createDHT(ctx context.Context, host host.Host, config DHTConfig) (*dual.DHT
 			}
 			bootstrapPeers = append(bootstrapPeers, *addrInfo)
 		}
-		options = append(options, dht.BootstrapPeers(bootstrapPeers...))
+	}
+	scopedBootstrapPeers := make([]peer.AddrInfo, 0, len(bootstrapPeers))
+	for _, addrInfo := range bootstrapPeers {
+		scopedAddrInfo := scope.filterAddrInfo(addrInfo)
+		// The default bootstrap peers are dropped in the private scope.
+		if len(scopedAddrInfo.Addrs) > 0 {
+			scopedBootstrapPeers = append(scopedBootstrapPeers, scopedAddrInfo)
+		}
+	}
+	options = append(options, dht.BootstrapPeers(scopedBootstrapPeers...))
+	if prefix != dht.DefaultPrefix {
+		options = append(options,
+			dht.ProtocolPrefix(prefix),
+			dht.NamespacedValidator(serverKeysNamespace, serverKeysValidator{}))
+		dualDHT, err := dual.New(ctx, host, dual.DHTOption(options...))
+		if err != nil {
+			return nil, err
+		}
+		return &DHTNode{dht: dualDHT, records: dualDHT}, nil
 	}
 	dualDHT, err := dual.New(ctx, host, dual.DHTOption(options...))
-	return dualDHT, err
+	if err != nil {
+		return nil, err
+	}
+	recordsOptions := make([]dht.Option, len(options), len(options)+3)
+	copy(recordsOptions, options)
+	recordsOptions = append(recordsOptions,
+		dht.ProtocolPrefix(messageHubDHTPrefix),
+		dht.NamespacedValidator(serverKeysNamespace, serverKeysValidator{}))
+	// Only other MessageHub peers join the records DHT, the default bootstrap peers are IPFS nodes.
+	if config.BootstrapPeers == nil {
+		recordsOptions = append(recordsOptions, dht.BootstrapPeers())
+	}
+	records, err := dual.New(ctx, host, dual.DHTOption(recordsOptions...))
+	if err != nil {
+		dualDHT.Close()
+		return nil, err
+	}
+	return &DHTNode{dht: dualDHT, records: records}, nil
+}
+
+// Returns the DHT storing values under the namespace of the key.
+func (dhtNode *DHTNode) getValueDHT(key string) (*dual.DHT, error) {
+	namespace, _, err := record.SplitKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if namespace == serverKeysNamespace {
+		return dhtNode.records, nil
+	}
+	return dhtNode.dht, nil
+}
+
+func (dhtNode *DHTNode) bootstrap(ctx context.Context) error {
+	err := dhtNode.dht.Bootstrap(ctx)
+	if err != nil || dhtNode.records == dhtNode.dht {
+		return err
+	}
+	return dhtNode.records.Bootstrap(ctx)
+}
+
+func (dhtNode *DHTNode) close() error {
+	err := dhtNode.dht.Close()
+	if dhtNode.records != dhtNode.dht {
+		if recordsErr := dhtNode.records.Close(); err == nil {
+			err = recordsErr
+		}
+	}
+	return err
 }
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	record "github.com/libp2p/go-libp2p-record"
+)
+
+func createTestDHT(t *testing.T, hostNode *HostNode, config DHTConfig) *DHTNode {
+	t.Helper()
+	dhtNode, err := createDHT(context.Background(), hostNode.host, config, AddressScopeAny, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		dhtNode.close()
+	})
+	return dhtNode
+}
+
+func TestServerKeysRecordsDHT(t *testing.T) {
+	key := "/" + serverKeysNamespace + "/example.com"
+	noBootstrapPeers := []string{}
+	customPrefix := "/messagehub-test"
+	tests := []struct {
+		name           string
+		config         DHTConfig
+		separateRecord bool
+	}{
+		{"DefaultPrefix", DHTConfig{BootstrapPeers: &noBootstrapPeers}, true},
+		{"CustomPrefix", DHTConfig{BootstrapPeers: &noBootstrapPeers, ProtocolPrefix: &customPrefix}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dhtNode := createTestDHT(t, createTestHost(t, HostConfig{}), test.config)
+			valueDHT, err := dhtNode.getValueDHT(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (valueDHT != dhtNode.dht) != test.separateRecord {
+				t.Fatalf("expected separate records DHT: %v", test.separateRecord)
+			}
+			validator, ok := valueDHT.WAN.Validator.(record.NamespacedValidator)
+			if !ok || validator[serverKeysNamespace] == nil {
+				t.Fatal("expected server keys validator")
+			}
+			if valueDHT, _ := dhtNode.getValueDHT("/pk/example.com"); valueDHT != dhtNode.dht {
+				t.Fatal("expected other records in the main DHT")
+			}
+		})
+	}
+}
+
+func TestServerKeysRecordsUnderDefaultPrefix(t *testing.T) {
+	serverMode := "server"
+	noBootstrapPeers := []string{}
+	config := DHTConfig{BootstrapPeers: &noBootstrapPeers, Mode: &serverMode}
+	publisher := createTestHost(t, HostConfig{})
+	resolver := createTestHost(t, HostConfig{})
+	publisherDHT := createTestDHT(t, publisher, config)
+	resolverDHT := createTestDHT(t, resolver, config)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := resolver.host.Connect(ctx, peer.AddrInfo{ID: publisher.host.ID(), Addrs: publisher.host.Addrs()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for publisherDHT.records.LAN.RoutingTable().Size() == 0 || resolverDHT.records.LAN.RoutingTable().Size() == 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("peers did not join the records DHT")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	identity := createTestIdentity(t, time.Now().Add(time.Hour).UnixMilli(), publisher.host.ID())
+	value, err := json.Marshal(identity.serverKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "/" + serverKeysNamespace + "/" + identity.id
+	valueDHT, err := publisherDHT.getValueDHT(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = valueDHT.PutValue(ctx, key, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valueDHT, err = resolverDHT.getValueDHT(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := valueDHT.GetValue(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != string(value) {
+		t.Fatalf("expected %s, got %s", value, result)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/discovery"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-kad-dht/dual"
+	"github.com/libp2p/go-libp2p/p2p/discovery/backoff"
+	"github.com/libp2p/go-libp2p/p2p/discovery/routing"
+)
+
+type FindPeersOptions struct {
+	Limit            *int
+	TimeoutSeconds   *int
+	ExcludeSelf      bool
+	RequireAddresses bool
+}
+
+type peerDiscovery struct {
+	discovery.Discovery
+	self peer.ID
+}
+
+// Repeated lookups of the same rendezvous string within the backoff are answered from cache.
+func newPeerDiscovery(d discovery.Discovery, self peer.ID) (*peerDiscovery, error) {
+	strategy := backoff.NewExponentialBackoff(
+		time.Minute, 10*time.Minute, backoff.FullJitter,
+		time.Second, 2, 0, rand.NewSource(time.Now().UnixNano()))
+	cached, err := backoff.NewBackoffDiscovery(d, strategy)
+	if err != nil {
+		return nil, err
+	}
+	return &peerDiscovery{
+		Discovery: cached,
+		self:      self,
+	}, nil
+}
+
+func createDiscovery(dualDHT *dual.DHT) (*peerDiscovery, error) {
+	return newPeerDiscovery(routing.NewRoutingDiscovery(dualDHT), dualDHT.WAN.Host().ID())
+}
+
+func createRendezvousDiscovery(host host.Host, scope AddressScope, rendezvousPoints []string) (*peerDiscovery, error) {
+	d, err := newRendezvousDiscovery(host, scope, rendezvousPoints)
+	if err != nil {
+		return nil, err
+	}
+	return newPeerDiscovery(d, host.ID())
+}
+
+// Duplicates are always dropped. The limit is applied after filtering, it is not passed to the
+// underlying discovery so that cached results stay complete.
+func (d *peerDiscovery) findPeers(ctx context.Context, topic string, options FindPeersOptions) (<-chan peer.AddrInfo, error) {
+	cancel := context.CancelFunc(func() {})
+	if options.TimeoutSeconds != nil {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(*options.TimeoutSeconds)*time.Second)
+	}
+	peers, err := d.FindPeers(ctx, topic)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	result := make(chan peer.AddrInfo)
+	go func() {
+		defer cancel()
+		defer close(result)
+		seen := make(map[peer.ID]struct{})
+		if options.Limit != nil && *options.Limit <= 0 {
+			return
+		}
+		for addrInfo := range peers {
+			if _, ok := seen[addrInfo.ID]; ok {
+				continue
+			}
+			if options.ExcludeSelf && addrInfo.ID == d.self {
+				continue
+			}
+			if options.RequireAddresses && len(addrInfo.Addrs) == 0 {
+				continue
+			}
+			seen[addrInfo.ID] = struct{}{}
+			select {
+			case result <- addrInfo:
+			case <-ctx.Done():
+				return
+			}
+			if options.Limit != nil && len(seen) >= *options.Limit {
+				return
+			}
+		}
+	}()
+	return result, nil
+}
@@ -1,17 +1,27 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/ipfs/go-cid"
 	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/peerstore"
 	p2phttp "github.com/libp2p/go-libp2p-http"
+	"github.com/libp2p/go-libp2p-kad-dht/dual"
+	mh "github.com/multiformats/go-multihash"
 )
 
-func download(ctx context.Context, host host.Host, peerID, url, filePath string) error {
+// The content is verified against contentID, unless it is cid.Undef.
+func download(ctx context.Context, host host.Host, peerID, url, filePath string, contentID cid.Cid) error {
 	transport := &http.Transport{}
 	transport.RegisterProtocol("libp2p", p2phttp.NewTransport(host))
 	client := &http.Client{Transport: transport}
@@ -32,15 +42,101 @@ func download(ctx context.Context, host host.Host, peerID, url, filePath string)
 		return fmt.Errorf("status: %v", response.Status)
 	}
 
-	file, err := os.Create(filePath)
+	// Written to a temporary file renamed into place once complete and verified,
+	// so a failed download never leaves a partial file at filePath.
+	file, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*.download")
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	success := false
+	defer func() {
+		if !success {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
 
-	_, err = io.Copy(file, response.Body)
+	var writer io.Writer = file
+	var hasher hash.Hash
+	if contentID.Defined() {
+		hasher, err = mh.GetHasher(contentID.Prefix().MhType)
+		if err != nil {
+			return err
+		}
+		writer = io.MultiWriter(file, hasher)
+	}
+	_, err = io.Copy(writer, response.Body)
+	if err != nil {
+		return err
+	}
+	if hasher != nil {
+		err = verifyContent(contentID, hasher.Sum(nil))
+		if err != nil {
+			return err
+		}
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(file.Name(), filePath)
 	if err != nil {
 		return err
 	}
+	success = true
 	return nil
 }
+
+func verifyContent(contentID cid.Cid, digest []byte) error {
+	prefix := contentID.Prefix()
+	if prefix.MhLength >= 0 && prefix.MhLength < len(digest) {
+		digest = digest[:prefix.MhLength]
+	}
+	multihash, err := mh.Encode(digest, prefix.MhType)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(multihash, contentID.Hash()) {
+		return fmt.Errorf("content does not match %s", contentID)
+	}
+	return nil
+}
+
+const maxDownloadProviders = 20
+
+// Files are verified by hashing them whole, which only matches raw single block content IDs,
+// so other codecs such as dag-pb are rejected before downloading anything.
+func checkDownloadContentID(contentID cid.Cid) error {
+	if contentID.Type() != cid.Raw {
+		return fmt.Errorf("content ID %s is not a raw content ID", contentID)
+	}
+	return nil
+}
+
+// Tries each provider of the content in turn until one of them serves the file.
+func downloadFromProviders(ctx context.Context, host host.Host, dualDHT *dual.DHT, contentID cid.Cid, url, filePath string) error {
+	if err := checkDownloadContentID(contentID); err != nil {
+		return err
+	}
+	var errs []string
+	for addrInfo := range dualDHT.FindProvidersAsync(ctx, contentID, maxDownloadProviders) {
+		if addrInfo.ID == host.ID() {
+			continue
+		}
+		if len(addrInfo.Addrs) > 0 {
+			host.Peerstore().AddAddrs(addrInfo.ID, addrInfo.Addrs, peerstore.TempAddrTTL)
+		}
+		err := download(ctx, host, peer.Encode(addrInfo.ID), url, filePath, contentID)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", addrInfo.ID, err))
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("no providers found for %s", contentID)
+	}
+	return fmt.Errorf("download from providers of %s failed: %s", contentID, strings.Join(errs, "; "))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func TestVerifyContent(t *testing.T) {
+	data := []byte("content")
+	contentID, err := cid.Prefix{Version: 1, Codec: cid.Raw, MhType: mh.SHA2_256, MhLength: -1}.Sum(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(data)
+	if err := verifyContent(contentID, digest[:]); err != nil {
+		t.Fatal(err)
+	}
+	digest = sha256.Sum256([]byte("other content"))
+	if err := verifyContent(contentID, digest[:]); err == nil {
+		t.Fatal("expected other content not to match")
+	}
+}
+
+func TestDownloadRejectsNonRawContentID(t *testing.T) {
+	contentID, err := cid.Prefix{Version: 1, Codec: cid.DagProtobuf, MhType: mh.SHA2_256, MhLength: -1}.Sum([]byte("content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostNode := createTestHost(t, HostConfig{})
+	noBootstrapPeers := []string{}
+	dhtNode := createTestDHT(t, hostNode, DHTConfig{BootstrapPeers: &noBootstrapPeers})
+	filePath := filepath.Join(t.TempDir(), "file")
+	err = downloadFromProviders(context.Background(), hostNode.host, dhtNode.dht, contentID, "/file", filePath)
+	if err == nil || err.Error() != checkDownloadContentID(contentID).Error() {
+		t.Fatalf("expected non raw content ID to be rejected, got %v", err)
+	}
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Fatal("expected no file to be written")
+	}
+}
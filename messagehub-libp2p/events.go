@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/libp2p/go-libp2p-core/event"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const eventBufferSize = 256
+
+// Emitted on the host event bus when mDNS finds a peer.
+type EvtMdnsPeerFound struct {
+	AddrInfo peer.AddrInfo
+}
+
+type hostEvent struct {
+	Type          string
+	PeerID        string   `json:",omitempty"`
+	Address       string   `json:",omitempty"`
+	Addresses     []string `json:",omitempty"`
+	Direction     string   `json:",omitempty"`
+	Connectedness string   `json:",omitempty"`
+	Reachability  string   `json:",omitempty"`
+	Error         string   `json:",omitempty"`
+}
+
+var errEventSubscriptionCancelled = errors.New("event subscription cancelled")
+
+type eventSubscription struct {
+	host         host.Host
+	subscription event.Subscription
+	events       chan hostEvent
+	done         chan struct{}
+	once         sync.Once
+}
+
+func newEventSubscription(host host.Host) (*eventSubscription, error) {
+	eventTypes := []any{
+		new(event.EvtPeerConnectednessChanged),
+		new(event.EvtPeerIdentificationCompleted),
+		new(event.EvtPeerIdentificationFailed),
+		new(event.EvtLocalReachabilityChanged),
+		new(event.EvtLocalAddressesUpdated),
+		new(EvtMdnsPeerFound),
+	}
+	subscription, err := host.EventBus().Subscribe(eventTypes)
+	if err != nil {
+		return nil, err
+	}
+	s := &eventSubscription{
+		host:         host,
+		subscription: subscription,
+		events:       make(chan hostEvent, eventBufferSize),
+		done:         make(chan struct{}),
+	}
+	host.Network().Notify(s)
+	go func() {
+		for e := range subscription.Out() {
+			if result, ok := convertEvent(e); ok {
+				s.push(result)
+			}
+		}
+	}()
+	return s, nil
+}
+
+func convertEvent(e any) (hostEvent, bool) {
+	switch e := e.(type) {
+	case event.EvtPeerConnectednessChanged:
+		return hostEvent{
+			Type:          "PeerConnectednessChanged",
+			PeerID:        peer.Encode(e.Peer),
+			Connectedness: e.Connectedness.String(),
+		}, true
+	case event.EvtPeerIdentificationCompleted:
+		return hostEvent{
+			Type:   "PeerIdentificationCompleted",
+			PeerID: peer.Encode(e.Peer),
+		}, true
+	case event.EvtPeerIdentificationFailed:
+		result := hostEvent{
+			Type:   "PeerIdentificationFailed",
+			PeerID: peer.Encode(e.Peer),
+		}
+		if e.Reason != nil {
+			result.Error = e.Reason.Error()
+		}
+		return result, true
+	case event.EvtLocalReachabilityChanged:
+		return hostEvent{
+			Type:         "LocalReachabilityChanged",
+			Reachability: e.Reachability.String(),
+		}, true
+	case event.EvtLocalAddressesUpdated:
+		addrs := make([]string, 0, len(e.Current))
+		for _, addr := range e.Current {
+			addrs = append(addrs, addr.Address.String())
+		}
+		return hostEvent{
+			Type:      "LocalAddressesUpdated",
+			Addresses: addrs,
+		}, true
+	case EvtMdnsPeerFound:
+		addrs := make([]string, 0, len(e.AddrInfo.Addrs))
+		for _, addr := range e.AddrInfo.Addrs {
+			addrs = append(addrs, addr.String())
+		}
+		return hostEvent{
+			Type:      "MdnsPeerFound",
+			PeerID:    peer.Encode(e.AddrInfo.ID),
+			Addresses: addrs,
+		}, true
+	}
+	return hostEvent{}, false
+}
+
+// Events are dropped if the buffer is full, so that slow consumers never block the host.
+func (s *eventSubscription) push(e hostEvent) {
+	select {
+	case <-s.done:
+	case s.events <- e:
+	default:
+	}
+}
+
+func (s *eventSubscription) next(ctx context.Context) (hostEvent, error) {
+	select {
+	case <-ctx.Done():
+		return hostEvent{}, ctx.Err()
+	case <-s.done:
+		return hostEvent{}, errEventSubscriptionCancelled
+	case e := <-s.events:
+		return e, nil
+	}
+}
+
+func (s *eventSubscription) cancel() {
+	s.once.Do(func() {
+		close(s.done)
+		s.host.Network().StopNotify(s)
+		s.subscription.Close()
+	})
+}
+
+func (s *eventSubscription) Listen(network.Network, ma.Multiaddr) {}
+
+func (s *eventSubscription) ListenClose(network.Network, ma.Multiaddr) {}
+
+func (s *eventSubscription) Connected(_ network.Network, conn network.Conn) {
+	s.push(hostEvent{
+		Type:      "Connected",
+		PeerID:    peer.Encode(conn.RemotePeer()),
+		Address:   conn.RemoteMultiaddr().String(),
+		Direction: conn.Stat().Direction.String(),
+	})
+}
+
+func (s *eventSubscription) Disconnected(_ network.Network, conn network.Conn) {
+	s.push(hostEvent{
+		Type:      "Disconnected",
+		PeerID:    peer.Encode(conn.RemotePeer()),
+		Address:   conn.RemoteMultiaddr().String(),
+		Direction: conn.Stat().Direction.String(),
+	})
+}
+
+func (s *eventSubscription) OpenedStream(network.Network, network.Stream) {}
+
+func (s *eventSubscription) ClosedStream(network.Network, network.Stream) {}
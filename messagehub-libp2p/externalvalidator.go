@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+)
+
+const validationQueueSize = 256
+
+var errValidatorClosed = errors.New("validator closed")
+
+type pendingValidation struct {
+	ID           int64
+	Topic        string
+	From         string
+	ReceivedFrom string
+	Data         string
+	result       chan pubsub.ValidationResult
+}
+
+type validationDropCounters struct {
+	QueueFull uint64
+	TimedOut  uint64
+	// Messages accepted without a verdict because the validator was closed.
+	Closed uint64
+}
+
+// Queues messages to be validated by the caller, which pulls them with next and answers with setResult.
+// Messages that can not be queued or get no verdict within the timeout are ignored and counted as dropped.
+// Pubsub marks messages as seen before validating them, so a dropped message is not delivered again
+// until it expires from the seen messages cache.
+// Topic validators using it stay registered after it is closed, so a closed validator accepts
+// every message and leaves the decision to the other validators in the chain.
+type externalValidator struct {
+	timeout time.Duration
+	drops   validationDropCounters
+	queue   chan *pendingValidation
+	mutex   sync.Mutex
+	nextID  int64
+	pending map[int64]*pendingValidation
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newExternalValidator(timeout time.Duration) (*externalValidator, error) {
+	if timeout <= 0 {
+		return nil, fmt.Errorf("invalid validation timeout: %v", timeout)
+	}
+	return &externalValidator{
+		timeout: timeout,
+		queue:   make(chan *pendingValidation, validationQueueSize),
+		pending: make(map[int64]*pendingValidation),
+		done:    make(chan struct{}),
+	}, nil
+}
+
+func (validator *externalValidator) validate(ctx context.Context, _ peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+	select {
+	case <-validator.done:
+		validator.countDrop(&validator.drops.Closed)
+		return pubsub.ValidationAccept
+	default:
+	}
+	validator.mutex.Lock()
+	validator.nextID += 1
+	pending := &pendingValidation{
+		ID:           validator.nextID,
+		Topic:        msg.GetTopic(),
+		From:         peer.Encode(msg.GetFrom()),
+		ReceivedFrom: peer.Encode(msg.ReceivedFrom),
+		Data:         string(msg.Data),
+		result:       make(chan pubsub.ValidationResult, 1),
+	}
+	validator.pending[pending.ID] = pending
+	validator.mutex.Unlock()
+	defer func() {
+		validator.mutex.Lock()
+		defer validator.mutex.Unlock()
+		delete(validator.pending, pending.ID)
+	}()
+
+	// A full queue delays the message instead of dropping it, as long as it fits in the timeout.
+	timer := time.NewTimer(validator.timeout)
+	defer timer.Stop()
+	select {
+	case validator.queue <- pending:
+	case <-timer.C:
+		validator.countDrop(&validator.drops.QueueFull)
+		return pubsub.ValidationIgnore
+	case <-ctx.Done():
+		validator.countDrop(&validator.drops.TimedOut)
+		return pubsub.ValidationIgnore
+	case <-validator.done:
+		validator.countDrop(&validator.drops.Closed)
+		return pubsub.ValidationAccept
+	}
+	select {
+	case result := <-pending.result:
+		return result
+	case <-timer.C:
+		validator.countDrop(&validator.drops.TimedOut)
+	case <-ctx.Done():
+		validator.countDrop(&validator.drops.TimedOut)
+	case <-validator.done:
+		validator.countDrop(&validator.drops.Closed)
+		return pubsub.ValidationAccept
+	}
+	return pubsub.ValidationIgnore
+}
+
+func (validator *externalValidator) countDrop(counter *uint64) {
+	validator.mutex.Lock()
+	defer validator.mutex.Unlock()
+	*counter += 1
+}
+
+func (validator *externalValidator) getDropCounters() validationDropCounters {
+	validator.mutex.Lock()
+	defer validator.mutex.Unlock()
+	return validator.drops
+}
+
+// Skips messages that timed out while queued.
+func (validator *externalValidator) next(ctx context.Context) (*pendingValidation, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-validator.done:
+			return nil, errValidatorClosed
+		case pending := <-validator.queue:
+			validator.mutex.Lock()
+			_, ok := validator.pending[pending.ID]
+			validator.mutex.Unlock()
+			if ok {
+				return pending, nil
+			}
+		}
+	}
+}
+
+func parseValidationResult(s string) (pubsub.ValidationResult, error) {
+	switch s {
+	case "Accept":
+		return pubsub.ValidationAccept, nil
+	case "Reject":
+		return pubsub.ValidationReject, nil
+	case "Ignore":
+		return pubsub.ValidationIgnore, nil
+	default:
+		return 0, fmt.Errorf("invalid validation result: %s", s)
+	}
+}
+
+func (validator *externalValidator) setResult(id int64, result pubsub.ValidationResult) error {
+	validator.mutex.Lock()
+	defer validator.mutex.Unlock()
+	pending, ok := validator.pending[id]
+	if !ok {
+		return fmt.Errorf("validation %d not found or expired", id)
+	}
+	delete(validator.pending, id)
+	pending.result <- result
+	return nil
+}
+
+func (validator *externalValidator) close() {
+	validator.once.Do(func() {
+		close(validator.done)
+	})
+}
+
+// Runs validators in order, stopping at the first that does not accept the message.
+func chainValidators(validators ...pubsub.ValidatorEx) pubsub.ValidatorEx {
+	return func(ctx context.Context, p peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+		for _, validator := range validators {
+			result := validator(ctx, p, msg)
+			if result != pubsub.ValidationAccept {
+				return result
+			}
+		}
+		return pubsub.ValidationAccept
+	}
+}
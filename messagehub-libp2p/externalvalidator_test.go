@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+)
+
+func createTestPubSubMessage(t *testing.T, data string) *pubsub.Message {
+	t.Helper()
+	topic := "topic"
+	from := createTestPeerID(t)
+	return &pubsub.Message{
+		Message:      &pb.Message{Data: []byte(data), Topic: &topic, From: []byte(from)},
+		ReceivedFrom: from,
+	}
+}
+
+func createTestExternalValidator(t *testing.T, timeout time.Duration) *externalValidator {
+	t.Helper()
+	validator, err := newExternalValidator(timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(validator.close)
+	return validator
+}
+
+// Runs validate in the background and returns a channel receiving its result.
+func startValidation(validator *externalValidator, msg *pubsub.Message) <-chan pubsub.ValidationResult {
+	result := make(chan pubsub.ValidationResult, 1)
+	go func() {
+		result <- validator.validate(context.Background(), msg.ReceivedFrom, msg)
+	}()
+	return result
+}
+
+func waitValidationResult(t *testing.T, result <-chan pubsub.ValidationResult) pubsub.ValidationResult {
+	t.Helper()
+	select {
+	case r := <-result:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("validation did not finish")
+		return 0
+	}
+}
+
+func nextTestValidation(t *testing.T, validator *externalValidator) *pendingValidation {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	pending, err := validator.next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pending
+}
+
+func TestParseValidationResult(t *testing.T) {
+	results := map[string]pubsub.ValidationResult{
+		"Accept": pubsub.ValidationAccept,
+		"Reject": pubsub.ValidationReject,
+		"Ignore": pubsub.ValidationIgnore,
+	}
+	for s, expected := range results {
+		result, err := parseValidationResult(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != expected {
+			t.Fatalf("%s: expected %v, got %v", s, expected, result)
+		}
+	}
+	for _, s := range []string{"", "accept", "Throttle"} {
+		if _, err := parseValidationResult(s); err == nil {
+			t.Fatalf("expected an error for %q", s)
+		}
+	}
+}
+
+func TestExternalValidatorResult(t *testing.T) {
+	validator := createTestExternalValidator(t, 5*time.Second)
+	msg := createTestPubSubMessage(t, "data")
+	result := startValidation(validator, msg)
+	pending := nextTestValidation(t, validator)
+	if pending.Topic != "topic" || pending.Data != "data" || pending.From != peer.Encode(msg.GetFrom()) {
+		t.Fatalf("unexpected validation: %+v", pending)
+	}
+	err := validator.setResult(pending.ID, pubsub.ValidationReject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := waitValidationResult(t, result); r != pubsub.ValidationReject {
+		t.Fatalf("expected reject, got %v", r)
+	}
+	if err := validator.setResult(pending.ID, pubsub.ValidationAccept); err == nil {
+		t.Fatal("expected an error for a completed validation")
+	}
+}
+
+func fillValidationQueue(validator *externalValidator) {
+	// Entries missing from the pending map are skipped by next.
+	for i := 0; i < validationQueueSize; i++ {
+		validator.queue <- &pendingValidation{ID: -int64(i)}
+	}
+}
+
+func TestExternalValidatorWaitsForQueue(t *testing.T) {
+	validator := createTestExternalValidator(t, 5*time.Second)
+	fillValidationQueue(validator)
+	result := startValidation(validator, createTestPubSubMessage(t, "data"))
+	time.Sleep(50 * time.Millisecond)
+	// Draining the queue makes room for the waiting message.
+	pending := nextTestValidation(t, validator)
+	err := validator.setResult(pending.ID, pubsub.ValidationAccept)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := waitValidationResult(t, result); r != pubsub.ValidationAccept {
+		t.Fatalf("expected accept, got %v", r)
+	}
+	if drops := validator.getDropCounters(); drops != (validationDropCounters{}) {
+		t.Fatalf("unexpected drops: %+v", drops)
+	}
+}
+
+func TestExternalValidatorQueueFull(t *testing.T) {
+	validator := createTestExternalValidator(t, 50*time.Millisecond)
+	fillValidationQueue(validator)
+	result := startValidation(validator, createTestPubSubMessage(t, "data"))
+	if r := waitValidationResult(t, result); r != pubsub.ValidationIgnore {
+		t.Fatalf("expected ignore, got %v", r)
+	}
+	if drops := validator.getDropCounters(); drops != (validationDropCounters{QueueFull: 1}) {
+		t.Fatalf("unexpected drops: %+v", drops)
+	}
+}
+
+func TestExternalValidatorTimeout(t *testing.T) {
+	validator := createTestExternalValidator(t, 50*time.Millisecond)
+	result := startValidation(validator, createTestPubSubMessage(t, "data"))
+	pending := nextTestValidation(t, validator)
+	if r := waitValidationResult(t, result); r != pubsub.ValidationIgnore {
+		t.Fatalf("expected ignore, got %v", r)
+	}
+	if err := validator.setResult(pending.ID, pubsub.ValidationAccept); err == nil {
+		t.Fatal("expected an error for an expired validation")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	msg := createTestPubSubMessage(t, "data")
+	if r := validator.validate(ctx, msg.ReceivedFrom, msg); r != pubsub.ValidationIgnore {
+		t.Fatalf("expected ignore, got %v", r)
+	}
+	if drops := validator.getDropCounters(); drops != (validationDropCounters{TimedOut: 2}) {
+		t.Fatalf("unexpected drops: %+v", drops)
+	}
+}
+
+func TestExternalValidatorPassesThroughWhenClosed(t *testing.T) {
+	validator := createTestExternalValidator(t, 5*time.Second)
+	result := startValidation(validator, createTestPubSubMessage(t, "data"))
+	nextTestValidation(t, validator)
+	validator.close()
+	if r := waitValidationResult(t, result); r != pubsub.ValidationAccept {
+		t.Fatalf("expected accept for a pending validation, got %v", r)
+	}
+
+	reject := func(context.Context, peer.ID, *pubsub.Message) pubsub.ValidationResult {
+		return pubsub.ValidationReject
+	}
+	accept := func(context.Context, peer.ID, *pubsub.Message) pubsub.ValidationResult {
+		return pubsub.ValidationAccept
+	}
+	msg := createTestPubSubMessage(t, "data")
+	if r := chainValidators(accept, validator.validate)(context.Background(), msg.ReceivedFrom, msg); r != pubsub.ValidationAccept {
+		t.Fatalf("expected accept, got %v", r)
+	}
+	if r := chainValidators(reject, validator.validate)(context.Background(), msg.ReceivedFrom, msg); r != pubsub.ValidationReject {
+		t.Fatalf("expected the other validators to still apply, got %v", r)
+	}
+	if drops := validator.getDropCounters(); drops != (validationDropCounters{Closed: 2}) {
+		t.Fatalf("unexpected drops: %+v", drops)
+	}
+	if _, err := validator.next(context.Background()); !errors.Is(err, errValidatorClosed) {
+		t.Fatalf("expected errValidatorClosed, got %v", err)
+	}
+}
@@ -1,31 +1,340 @@
 package main
 
 import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+
+	"github.com/libp2p/go-libp2p-core/connmgr"
 	"github.com/libp2p/go-libp2p-core/control"
+	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/network"
 	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p/p2p/net/conngater"
 	ma "github.com/multiformats/go-multiaddr"
 	manet "github.com/multiformats/go-multiaddr/net"
 )
 
-type privateAddressGater struct{}
+type addressScopeGater struct {
+	scope AddressScope
+}
+
+func (gater *addressScopeGater) InterceptPeerDial(p peer.ID) (allow bool) {
+	return true
+}
+
+func (gater *addressScopeGater) InterceptAddrDial(_ peer.ID, addr ma.Multiaddr) (allow bool) {
+	return gater.scope.allows(addr)
+}
+
+func (gater *addressScopeGater) InterceptAccept(addrs network.ConnMultiaddrs) (allow bool) {
+	return gater.scope.allows(addrs.RemoteMultiaddr())
+}
+
+func (gater *addressScopeGater) InterceptSecured(_ network.Direction, _ peer.ID, addrs network.ConnMultiaddrs) (allow bool) {
+	return gater.scope.allows(addrs.RemoteMultiaddr())
+}
+
+func (gater *addressScopeGater) InterceptUpgraded(conn network.Conn) (allow bool, reason control.DisconnectReason) {
+	return gater.scope.allows(conn.RemoteMultiaddr()), 0
+}
+
+// Allows a connection only if every gater in the chain allows it.
+type gaterChain []connmgr.ConnectionGater
+
+func (chain gaterChain) InterceptPeerDial(p peer.ID) (allow bool) {
+	for _, gater := range chain {
+		if !gater.InterceptPeerDial(p) {
+			return false
+		}
+	}
+	return true
+}
+
+func (chain gaterChain) InterceptAddrDial(p peer.ID, addr ma.Multiaddr) (allow bool) {
+	for _, gater := range chain {
+		if !gater.InterceptAddrDial(p, addr) {
+			return false
+		}
+	}
+	return true
+}
+
+func (chain gaterChain) InterceptAccept(addrs network.ConnMultiaddrs) (allow bool) {
+	for _, gater := range chain {
+		if !gater.InterceptAccept(addrs) {
+			return false
+		}
+	}
+	return true
+}
+
+func (chain gaterChain) InterceptSecured(direction network.Direction, p peer.ID, addrs network.ConnMultiaddrs) (allow bool) {
+	for _, gater := range chain {
+		if !gater.InterceptSecured(direction, p, addrs) {
+			return false
+		}
+	}
+	return true
+}
+
+func (chain gaterChain) InterceptUpgraded(conn network.Conn) (allow bool, reason control.DisconnectReason) {
+	for _, gater := range chain {
+		if allow, reason := gater.InterceptUpgraded(conn); !allow {
+			return false, reason
+		}
+	}
+	return true, 0
+}
+
+type blockList struct {
+	Peers   []string
+	Subnets []string
+}
+
+func getBlockList(gater *conngater.BasicConnectionGater) blockList {
+	result := blockList{
+		Peers:   make([]string, 0),
+		Subnets: make([]string, 0),
+	}
+	for _, peerID := range gater.ListBlockedPeers() {
+		result.Peers = append(result.Peers, peer.Encode(peerID))
+	}
+	for _, ip := range gater.ListBlockedAddrs() {
+		result.Subnets = append(result.Subnets, ip.String())
+	}
+	for _, ipNet := range gater.ListBlockedSubnets() {
+		result.Subnets = append(result.Subnets, ipNet.String())
+	}
+	return result
+}
+
+// Blocks the peer and closes existing connections to it.
+func blockPeer(host host.Host, gater *conngater.BasicConnectionGater, peerID peer.ID) error {
+	err := gater.BlockPeer(peerID)
+	if err != nil {
+		return err
+	}
+	return host.Network().ClosePeer(peerID)
+}
+
+// Blocks the subnet (in CIDR notation) and closes existing connections from it.
+func blockSubnet(host host.Host, gater *conngater.BasicConnectionGater, cidr string) error {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+	err = gater.BlockSubnet(ipNet)
+	if err != nil {
+		return err
+	}
+	for _, conn := range host.Network().Conns() {
+		ip, err := manet.ToIP(conn.RemoteMultiaddr())
+		if err == nil && ipNet.Contains(ip) {
+			conn.Close()
+		}
+	}
+	return nil
+}
+
+func unblockSubnet(gater *conngater.BasicConnectionGater, cidr string) error {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+	return gater.UnblockSubnet(ipNet)
+}
+
+const (
+	allowListPeerPrefix   = "/messagehub/allowList/peer"
+	allowListSubnetPrefix = "/messagehub/allowList/subnet"
+)
+
+// Allow list persisted in the datastore. When enabled, only allowed peers,
+// or peers connecting from an allowed subnet, pass the gater.
+type allowListGater struct {
+	ds      datastore.Datastore
+	enabled bool
+	mutex   sync.RWMutex
+	peers   map[peer.ID]struct{}
+	subnets map[string]*net.IPNet
+}
+
+func newAllowListGater(ds datastore.Datastore, enabled bool) (*allowListGater, error) {
+	gater := &allowListGater{
+		ds:      ds,
+		enabled: enabled,
+		peers:   make(map[peer.ID]struct{}),
+		subnets: make(map[string]*net.IPNet),
+	}
+	ctx := context.Background()
+	peerResults, err := ds.Query(ctx, query.Query{Prefix: allowListPeerPrefix})
+	if err != nil {
+		return nil, fmt.Errorf("error querying allowed peers: %w", err)
+	}
+	for result := range peerResults.Next() {
+		if result.Error != nil {
+			return nil, fmt.Errorf("error querying allowed peers: %w", result.Error)
+		}
+		peerID, err := peer.IDFromBytes(result.Value)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding allowed peer: %w", err)
+		}
+		gater.peers[peerID] = struct{}{}
+	}
+	subnetResults, err := ds.Query(ctx, query.Query{Prefix: allowListSubnetPrefix})
+	if err != nil {
+		return nil, fmt.Errorf("error querying allowed subnets: %w", err)
+	}
+	for result := range subnetResults.Next() {
+		if result.Error != nil {
+			return nil, fmt.Errorf("error querying allowed subnets: %w", result.Error)
+		}
+		_, ipNet, err := net.ParseCIDR(string(result.Value))
+		if err != nil {
+			return nil, fmt.Errorf("error decoding allowed subnet: %w", err)
+		}
+		gater.subnets[ipNet.String()] = ipNet
+	}
+	return gater, nil
+}
+
+func allowListPeerKey(peerID peer.ID) datastore.Key {
+	return datastore.NewKey(allowListPeerPrefix).ChildString(peer.Encode(peerID))
+}
+
+// CIDR notation contains a slash, which is a key separator.
+func allowListSubnetKey(ipNet *net.IPNet) datastore.Key {
+	return datastore.NewKey(allowListSubnetPrefix).ChildString(hex.EncodeToString([]byte(ipNet.String())))
+}
 
-func (gater *privateAddressGater) InterceptPeerDial(p peer.ID) (allow bool) {
+func (gater *allowListGater) allowPeer(peerID peer.ID) error {
+	gater.mutex.Lock()
+	defer gater.mutex.Unlock()
+	err := gater.ds.Put(context.Background(), allowListPeerKey(peerID), []byte(peerID))
+	if err != nil {
+		return fmt.Errorf("error saving allowed peer: %w", err)
+	}
+	gater.peers[peerID] = struct{}{}
+	return nil
+}
+
+func (gater *allowListGater) disallowPeer(peerID peer.ID) error {
+	gater.mutex.Lock()
+	defer gater.mutex.Unlock()
+	err := gater.ds.Delete(context.Background(), allowListPeerKey(peerID))
+	if err != nil {
+		return fmt.Errorf("error deleting allowed peer: %w", err)
+	}
+	delete(gater.peers, peerID)
+	return nil
+}
+
+func (gater *allowListGater) allowSubnet(cidr string) error {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+	gater.mutex.Lock()
+	defer gater.mutex.Unlock()
+	err = gater.ds.Put(context.Background(), allowListSubnetKey(ipNet), []byte(ipNet.String()))
+	if err != nil {
+		return fmt.Errorf("error saving allowed subnet: %w", err)
+	}
+	gater.subnets[ipNet.String()] = ipNet
+	return nil
+}
+
+func (gater *allowListGater) disallowSubnet(cidr string) error {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+	gater.mutex.Lock()
+	defer gater.mutex.Unlock()
+	err = gater.ds.Delete(context.Background(), allowListSubnetKey(ipNet))
+	if err != nil {
+		return fmt.Errorf("error deleting allowed subnet: %w", err)
+	}
+	delete(gater.subnets, ipNet.String())
+	return nil
+}
+
+func (gater *allowListGater) allows(peerID peer.ID, addr ma.Multiaddr) bool {
+	if !gater.enabled {
+		return true
+	}
+	gater.mutex.RLock()
+	defer gater.mutex.RUnlock()
+	if _, ok := gater.peers[peerID]; ok {
+		return true
+	}
+	ip, err := manet.ToIP(addr)
+	if err != nil {
+		return false
+	}
+	for _, ipNet := range gater.subnets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Closes existing connections that are no longer allowed.
+func (gater *allowListGater) closeDisallowed(host host.Host) {
+	for _, conn := range host.Network().Conns() {
+		if !gater.allows(conn.RemotePeer(), conn.RemoteMultiaddr()) {
+			conn.Close()
+		}
+	}
+}
+
+type allowList struct {
+	Enabled bool
+	Peers   []string
+	Subnets []string
+}
+
+func (gater *allowListGater) list() allowList {
+	gater.mutex.RLock()
+	defer gater.mutex.RUnlock()
+	result := allowList{
+		Enabled: gater.enabled,
+		Peers:   make([]string, 0, len(gater.peers)),
+		Subnets: make([]string, 0, len(gater.subnets)),
+	}
+	for peerID := range gater.peers {
+		result.Peers = append(result.Peers, peer.Encode(peerID))
+	}
+	for subnet := range gater.subnets {
+		result.Subnets = append(result.Subnets, subnet)
+	}
+	return result
+}
+
+func (gater *allowListGater) InterceptPeerDial(peer.ID) (allow bool) {
 	return true
 }
 
-func (gater *privateAddressGater) InterceptAddrDial(_ peer.ID, addr ma.Multiaddr) (allow bool) {
-	return manet.IsPrivateAddr(addr)
+func (gater *allowListGater) InterceptAddrDial(p peer.ID, addr ma.Multiaddr) (allow bool) {
+	return gater.allows(p, addr)
 }
 
-func (gater *privateAddressGater) InterceptAccept(addrs network.ConnMultiaddrs) (allow bool) {
-	return manet.IsPrivateAddr(addrs.RemoteMultiaddr())
+// The peer is unknown until the connection is secured.
+func (gater *allowListGater) InterceptAccept(network.ConnMultiaddrs) (allow bool) {
+	return true
 }
 
-func (gater *privateAddressGater) InterceptSecured(_ network.Direction, _ peer.ID, addrs network.ConnMultiaddrs) (allow bool) {
-	return manet.IsPrivateAddr(addrs.RemoteMultiaddr())
+func (gater *allowListGater) InterceptSecured(_ network.Direction, p peer.ID, addrs network.ConnMultiaddrs) (allow bool) {
+	return gater.allows(p, addrs.RemoteMultiaddr())
 }
 
-func (gater *privateAddressGater) InterceptUpgraded(conn network.Conn) (allow bool, reason control.DisconnectReason) {
-	return manet.IsPrivateAddr(conn.RemoteMultiaddr()), 0
+func (gater *allowListGater) InterceptUpgraded(network.Conn) (allow bool, reason control.DisconnectReason) {
+	return true, 0
 }
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func expectStreamAccepted(t *testing.T, from *HostNode, to *HostNode) {
+	t.Helper()
+	s, err := openTestStream(from, to)
+	if err != nil {
+		t.Fatalf("error opening stream: %v", err)
+	}
+	s.Close()
+}
+
+func expectStreamRejected(t *testing.T, from *HostNode, to *HostNode) {
+	t.Helper()
+	// Protocols learned from an earlier connection make the protocol negotiation lazy,
+	// so opening the stream would succeed without reaching the remote peer.
+	err := from.host.Peerstore().RemoveProtocols(to.host.ID(), testProtocol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := openTestStream(from, to)
+	if err == nil {
+		s.Reset()
+		t.Fatal("expected stream to fail")
+	}
+	if to.host.Network().Connectedness(from.host.ID()) == network.Connected {
+		t.Fatal("expected no connection")
+	}
+}
+
+func handleTestStreams(hostNode *HostNode) {
+	hostNode.host.SetStreamHandler(testProtocol, func(s network.Stream) {
+		s.Close()
+	})
+}
+
+func waitDisconnected(t *testing.T, from *HostNode, to peer.ID) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for from.host.Network().Connectedness(to) == network.Connected {
+		if time.Now().After(deadline) {
+			t.Fatal("connection was not closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAllowListDecisions(t *testing.T) {
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	gater, err := newAllowListGater(ds, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowedPeer := createTestPeerID(t)
+	otherPeer := createTestPeerID(t)
+	err = gater.allowPeer(allowedPeer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = gater.allowSubnet("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	relayed := ma.StringCast("/p2p/QmcgpsyWgH8Y8ajJz1Cu72KnS5uo2Aa2LpzU7kinSupNKC/p2p-circuit")
+	decisions := []struct {
+		peerID   peer.ID
+		addr     ma.Multiaddr
+		expected bool
+	}{
+		{allowedPeer, ma.StringCast("/ip4/1.2.3.4/tcp/4001"), true},
+		{allowedPeer, relayed, true},
+		{otherPeer, ma.StringCast("/ip4/10.1.2.3/tcp/4001"), true},
+		{otherPeer, ma.StringCast("/ip4/1.2.3.4/tcp/4001"), false},
+		{otherPeer, relayed, false},
+	}
+	for _, decision := range decisions {
+		if allowed := gater.InterceptAddrDial(decision.peerID, decision.addr); allowed != decision.expected {
+			t.Errorf("%s at %s: expected %v, got %v", decision.peerID, decision.addr, decision.expected, allowed)
+		}
+	}
+
+	err = gater.disallowSubnet("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gater.InterceptAddrDial(otherPeer, ma.StringCast("/ip4/10.1.2.3/tcp/4001")) {
+		t.Error("disallowed subnet should be denied")
+	}
+
+	disabled, err := newAllowListGater(ds, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !disabled.InterceptAddrDial(otherPeer, ma.StringCast("/ip4/1.2.3.4/tcp/4001")) {
+		t.Error("disabled allow list should allow every peer")
+	}
+}
+
+func TestAllowListPersistence(t *testing.T) {
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	gater, err := newAllowListGater(ds, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowedPeer := createTestPeerID(t)
+	err = gater.allowPeer(allowedPeer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = gater.allowSubnet("192.168.1.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, prefix := range []string{allowListPeerPrefix, allowListSubnetPrefix} {
+		results, err := ds.Query(ctx, query.Query{Prefix: prefix})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries, err := results.Rest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("expected one entry under %s, got %d", prefix, len(entries))
+		}
+	}
+
+	reloaded, err := newAllowListGater(ds, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := reloaded.list()
+	if len(list.Peers) != 1 || list.Peers[0] != peer.Encode(allowedPeer) {
+		t.Fatalf("unexpected peers: %v", list.Peers)
+	}
+	if len(list.Subnets) != 1 || list.Subnets[0] != "192.168.1.0/24" {
+		t.Fatalf("unexpected subnets: %v", list.Subnets)
+	}
+
+	err = reloaded.disallowPeer(allowedPeer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = reloaded.disallowSubnet("192.168.1.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err = newAllowListGater(ds, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list = reloaded.list()
+	if len(list.Peers) != 0 || len(list.Subnets) != 0 {
+		t.Fatalf("expected an empty allow list, got %+v", list)
+	}
+}
+
+func TestAllowListClosesDisallowedConnections(t *testing.T) {
+	server := createTestHost(t, HostConfig{EnableAllowList: true})
+	client := createTestHost(t, HostConfig{})
+	handleTestStreams(server)
+	expectStreamRejected(t, client, server)
+	err := server.allowList.allowPeer(client.host.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStreamAccepted(t, client, server)
+	err = server.allowList.disallowPeer(client.host.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.allowList.closeDisallowed(server.host)
+	waitDisconnected(t, server, client.host.ID())
+}
+
+func TestBlockClosesConnections(t *testing.T) {
+	server := createTestHost(t, HostConfig{})
+	blockedPeer := createTestHost(t, HostConfig{})
+	handleTestStreams(server)
+	expectStreamAccepted(t, blockedPeer, server)
+	err := blockPeer(server.host, server.gater, blockedPeer.host.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitDisconnected(t, server, blockedPeer.host.ID())
+	expectStreamRejected(t, blockedPeer, server)
+
+	blockedSubnetPeer := createTestHost(t, HostConfig{})
+	expectStreamAccepted(t, blockedSubnetPeer, server)
+	err = blockSubnet(server.host, server.gater, "127.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitDisconnected(t, server, blockedSubnetPeer.host.ID())
+	expectStreamRejected(t, blockedSubnetPeer, server)
+	list := getBlockList(server.gater)
+	if len(list.Peers) != 1 || len(list.Subnets) != 1 || list.Subnets[0] != "127.0.0.0/8" {
+		t.Fatalf("unexpected block list: %+v", list)
+	}
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"bytes"
 	"context"
-	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -24,16 +23,18 @@ import (
 	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p-core/peerstore"
-	"github.com/libp2p/go-libp2p-core/pnet"
 	gostream "github.com/libp2p/go-libp2p-gostream"
 	p2phttp "github.com/libp2p/go-libp2p-http"
 	"github.com/libp2p/go-libp2p-peerstore/pstoreds"
 	quic "github.com/libp2p/go-libp2p-quic-transport"
 	"github.com/libp2p/go-libp2p/p2p/host/autorelay"
+	"github.com/libp2p/go-libp2p/p2p/net/conngater"
+	"github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/relay"
+	"github.com/libp2p/go-libp2p/p2p/protocol/holepunch"
 	"github.com/libp2p/go-tcp-transport"
+	websocket "github.com/libp2p/go-ws-transport"
 	"github.com/multiformats/go-multiaddr"
 	manet "github.com/multiformats/go-multiaddr/net"
-	"golang.org/x/crypto/hkdf"
 )
 
 type SignedRequest struct {
@@ -48,10 +49,16 @@ type SignedRequest struct {
 }
 
 type HostNode struct {
-	ds         datastore.Batching
-	ps         peerstore.Peerstore
-	peerSource chan peer.AddrInfo
-	host       host.Host
+	ds            datastore.Batching
+	ps            peerstore.Peerstore
+	peerSource    chan peer.AddrInfo
+	host          host.Host
+	addressScope  AddressScope
+	gater         *conngater.BasicConnectionGater
+	allowList     *allowListGater
+	rateLimiter   *rateLimitGater
+	pnetAuth      *privateNetwork
+	networkStatus *networkStatusTracker
 }
 
 func createHost(config HostConfig) (*HostNode, error) {
@@ -61,6 +68,10 @@ func createHost(config HostConfig) (*HostNode, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error creating dataPath: %w", err)
 	}
+	identity, err := loadIdentity(dataPath, config)
+	if err != nil {
+		return nil, err
+	}
 	dbPath := filepath.Join(dataPath, "datastore.db")
 	ds, err := leveldb.NewDatastore(dbPath, nil)
 	if err != nil {
@@ -79,14 +90,78 @@ func createHost(config HostConfig) (*HostNode, error) {
 		}
 	}()
 
+	// Block list rules are persisted in the datastore.
+	gater, err := conngater.NewBasicConnectionGater(ds)
+	if err != nil {
+		return nil, fmt.Errorf("error creating ConnectionGater: %w", err)
+	}
+	addressScope, err := getAddressScope(config)
+	if err != nil {
+		return nil, err
+	}
+	// Allow list entries are kept while the allow list is disabled, so it can be populated beforehand.
+	allowList, err := newAllowListGater(ds, config.EnableAllowList)
+	if err != nil {
+		return nil, fmt.Errorf("error creating allow list: %w", err)
+	}
+	gaters := gaterChain{gater, allowList}
+	if addressScope != AddressScopeAny {
+		gaters = append(gaters, &addressScopeGater{scope: addressScope})
+	}
+	var rateLimiter *rateLimitGater
+	if config.ConnectionRateLimits != nil {
+		rateLimiter, err = newRateLimitGater(*config.ConnectionRateLimits)
+		if err != nil {
+			return nil, fmt.Errorf("error configuring connection rate limits: %w", err)
+		}
+		gaters = append(gaters, rateLimiter)
+	}
+
 	peerSource := make(chan peer.AddrInfo)
+	networkStatus := newNetworkStatusTracker()
 	options := []libp2p.Option{
 		libp2p.Peerstore(ps),
 		libp2p.EnableNATService(),
 		libp2p.AutoNATServiceRateLimit(20, 3, time.Minute),
-		libp2p.EnableHolePunching(),
+		libp2p.EnableHolePunching(holepunch.WithTracer(networkStatus)),
 		libp2p.EnableRelay(),
 	}
+	if identity != nil {
+		options = append(options, libp2p.Identity(identity))
+	}
+	if config.AnnounceAddresses != nil || config.NoAnnounceAddresses != nil {
+		var announce, noAnnounce []string
+		if config.AnnounceAddresses != nil {
+			announce = *config.AnnounceAddresses
+		}
+		if config.NoAnnounceAddresses != nil {
+			noAnnounce = *config.NoAnnounceAddresses
+		}
+		addrsFactory, err := createAddrsFactory(announce, noAnnounce)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, libp2p.AddrsFactory(addrsFactory))
+	}
+	if config.ConnectionManager != nil {
+		connManager, err := createConnManager(*config.ConnectionManager)
+		if err != nil {
+			return nil, fmt.Errorf("error creating connection manager: %w", err)
+		}
+		options = append(options, libp2p.ConnectionManager(connManager))
+	}
+	if config.ResourceLimits != nil {
+		resourceManager, err := createResourceManager(*config.ResourceLimits)
+		if err != nil {
+			return nil, fmt.Errorf("error creating resource manager: %w", err)
+		}
+		options = append(options, libp2p.ResourceManager(resourceManager))
+	}
+	options = append(options, getNATOptions(config)...)
+	if config.RelayService != nil {
+		resources := getRelayResources(*config.RelayService)
+		options = append(options, libp2p.EnableRelayService(relay.WithResources(resources)))
+	}
 	if config.StaticRelays != nil {
 		relayAddrInfos := make([]peer.AddrInfo, 0)
 		for _, s := range *config.StaticRelays {
@@ -94,7 +169,10 @@ func createHost(config HostConfig) (*HostNode, error) {
 			if err != nil {
 				return nil, fmt.Errorf("error parsing static relay address: %w", err)
 			}
-			relayAddrInfos = append(relayAddrInfos, *relayAddrInfo)
+			scopedAddrInfo := addressScope.filterAddrInfo(*relayAddrInfo)
+			if len(scopedAddrInfo.Addrs) > 0 {
+				relayAddrInfos = append(relayAddrInfos, scopedAddrInfo)
+			}
 		}
 		autoRelayOptions := []autorelay.Option{
 			autorelay.WithPeerSource(peerSource),
@@ -104,56 +182,82 @@ func createHost(config HostConfig) (*HostNode, error) {
 	} else {
 		options = append(options, libp2p.EnableAutoRelay(autorelay.WithPeerSource(peerSource)))
 	}
-	if config.PrivateNetworkSecret == nil {
-		listenAddresses := []string{
-			"/ip4/0.0.0.0/tcp/0",
-			"/ip4/0.0.0.0/udp/0/quic",
-			"/ip6/::/tcp/0",
-			"/ip6/::/udp/0/quic",
-		}
-		transportOptions := []libp2p.Option{
-			libp2p.ChainOptions(
-				libp2p.Transport(tcp.NewTCPTransport),
-				libp2p.Transport(quic.NewTransport)),
-			libp2p.ListenAddrStrings(listenAddresses...),
-		}
-		options = append(options, transportOptions...)
-	} else {
-		info := []byte("libp2p")
-		reader := hkdf.New(sha256.New, []byte(*config.PrivateNetworkSecret), nil, info)
-		psk := make([]byte, 32)
-		_, err := reader.Read(psk)
+	var privateNetwork *privateNetwork
+	var tcpTransport, quicTransport, webSocketTransport any = tcp.NewTCPTransport, quic.NewTransport, websocket.New
+	if config.PrivateNetworkSecret != nil {
+		keys, err := getPrivateNetworkKeys(config)
 		if err != nil {
 			return nil, fmt.Errorf("error configuring private network: %w", err)
 		}
-		options = append(options, libp2p.PrivateNetwork(pnet.PSK(psk)))
-		options = append(options, libp2p.ConnectionGater(&privateAddressGater{}))
-
-		listenAddresses := []string{
-			"/ip4/0.0.0.0/tcp/0",
-			"/ip6/::/tcp/0",
-		}
-		transportOptions := []libp2p.Option{
-			libp2p.Transport(tcp.NewTCPTransport),
-			libp2p.ListenAddrStrings(listenAddresses...),
-		}
-		options = append(options, transportOptions...)
+		// PSK is applied by the connection upgrader with the primary key,
+		// privateNetwork accepts the other keys and authenticates QUIC connections.
+		options = append(options, libp2p.PrivateNetwork(keys[0].psk))
+		privateNetwork = newPrivateNetwork(keys)
+		tcpTransport = privateNetwork.newTCPTransport
+		quicTransport = privateNetwork.newQuicTransport
+		webSocketTransport = privateNetwork.newWebSocketTransport
+	}
+	listenAddresses := []string{
+		"/ip4/0.0.0.0/tcp/0",
+		"/ip4/0.0.0.0/udp/0/quic",
+		"/ip6/::/tcp/0",
+		"/ip6/::/udp/0/quic",
 	}
+	transports := []libp2p.Option{
+		libp2p.Transport(tcpTransport),
+		libp2p.Transport(quicTransport),
+	}
+	if config.EnableWebSocket {
+		listenAddresses = append(listenAddresses, "/ip4/0.0.0.0/tcp/0/ws", "/ip6/::/tcp/0/ws")
+		transports = append(transports, libp2p.Transport(webSocketTransport))
+	}
+	if config.ListenAddresses != nil {
+		listenAddresses = *config.ListenAddresses
+	}
+	options = append(options,
+		libp2p.ChainOptions(transports...),
+		libp2p.ListenAddrStrings(listenAddresses...))
+	options = append(options, libp2p.ConnectionGater(gaters))
 	host, err := libp2p.New(options...)
 	if err != nil {
 		return nil, err
 	}
+	err = networkStatus.start(host)
+	if err != nil {
+		host.Close()
+		return nil, fmt.Errorf("error subscribing to host events: %w", err)
+	}
+	if privateNetwork != nil {
+		privateNetwork.start(host)
+	}
+	if config.RendezvousService != nil {
+		startRendezvousService(host, *config.RendezvousService)
+	}
 	success = true
 	hostNode := &HostNode{
-		ds:         ds,
-		ps:         ps,
-		host:       host,
-		peerSource: peerSource,
+		ds:            ds,
+		ps:            ps,
+		host:          host,
+		peerSource:    peerSource,
+		addressScope:  addressScope,
+		gater:         gater,
+		allowList:     allowList,
+		rateLimiter:   rateLimiter,
+		pnetAuth:      privateNetwork,
+		networkStatus: networkStatus,
 	}
 	return hostNode, nil
 }
 
-func connectToSavedPeers(ctx context.Context, host host.Host) int {
+// getNATOptions returns the port mapping options, UPnP/NAT-PMP is only used when enabled in the config.
+func getNATOptions(config HostConfig) []libp2p.Option {
+	if !config.EnableNATPortMap {
+		return nil
+	}
+	return []libp2p.Option{libp2p.NATPortMap()}
+}
+
+func connectToSavedPeers(ctx context.Context, host host.Host, scope AddressScope) int {
 	// Load saved AddrInfos from PeerStore.
 	maxCandidateCount := 20
 	savedAddrInfos := make([]peer.AddrInfo, 0, maxCandidateCount)
@@ -166,15 +270,21 @@ func connectToSavedPeers(ctx context.Context, host host.Host) int {
 				continue
 			}
 			addrInfo := ps.PeerInfo(peerID)
-			publicAddrs := multiaddr.FilterAddrs(addrInfo.Addrs, manet.IsPublicAddr)
-			if len(publicAddrs) == 0 {
+			// Private addresses of saved peers are likely stale unless restricted to private networks.
+			var scopedAddrs []multiaddr.Multiaddr
+			if scope == AddressScopePrivate {
+				scopedAddrs = scope.filterAddrs(addrInfo.Addrs)
+			} else {
+				scopedAddrs = multiaddr.FilterAddrs(addrInfo.Addrs, manet.IsPublicAddr)
+			}
+			if len(scopedAddrs) == 0 {
 				continue
 			}
-			publicAddrInfo := peer.AddrInfo{
+			scopedAddrInfo := peer.AddrInfo{
 				ID:    addrInfo.ID,
-				Addrs: publicAddrs,
+				Addrs: scopedAddrs,
 			}
-			savedAddrInfos = append(savedAddrInfos, publicAddrInfo)
+			savedAddrInfos = append(savedAddrInfos, scopedAddrInfo)
 			if len(savedAddrInfos) >= maxCandidateCount {
 				break
 			}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p"
+)
+
+func TestNATPortMapOption(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		var cfg libp2p.Config
+		err := cfg.Apply(getNATOptions(HostConfig{EnableNATPortMap: enabled})...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (cfg.NATManager != nil) != enabled {
+			t.Errorf("EnableNATPortMap = %v: NAT manager configured = %v", enabled, cfg.NATManager != nil)
+		}
+	}
+}
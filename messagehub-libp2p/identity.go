@@ -0,0 +1,96 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"golang.org/x/crypto/hkdf"
+)
+
+const identityKeyFileName = "identity.key"
+
+// Derive a libp2p Ed25519 key from the hex encoded seed of the Matrix identity key.
+// The seed is expanded with HKDF so that the same key material is never used by both protocols.
+func deriveIdentity(hexSeed string) (crypto.PrivKey, error) {
+	seed, err := hex.DecodeString(hexSeed)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding identity seed: %w", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid identity seed length: %d", len(seed))
+	}
+	info := []byte("libp2p-identity")
+	reader := hkdf.New(sha256.New, seed, nil, info)
+	privateKey, _, err := crypto.GenerateEd25519Key(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving identity: %w", err)
+	}
+	return privateKey, nil
+}
+
+func loadOrCreateIdentity(dataPath string) (crypto.PrivKey, error) {
+	keyPath := filepath.Join(dataPath, identityKeyFileName)
+	data, err := os.ReadFile(keyPath)
+	if err == nil {
+		privateKey, err := crypto.UnmarshalPrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing identity key: %w", err)
+		}
+		return privateKey, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error reading identity key: %w", err)
+	}
+
+	privateKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error generating identity key: %w", err)
+	}
+	data, err = crypto.MarshalPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding identity key: %w", err)
+	}
+	err = writeFileAtomic(keyPath, data, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("error saving identity key: %w", err)
+	}
+	return privateKey, nil
+}
+
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tempPath := path + ".tmp"
+	file, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return os.Rename(tempPath, path)
+}
+
+// Returns nil if the host should use a new random identity.
+func loadIdentity(dataPath string, config HostConfig) (crypto.PrivKey, error) {
+	if config.IdentitySeed != nil {
+		return deriveIdentity(*config.IdentitySeed)
+	}
+	if config.PersistIdentity {
+		return loadOrCreateIdentity(dataPath)
+	}
+	return nil, nil
+}
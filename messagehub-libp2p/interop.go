@@ -55,11 +55,15 @@ type ProxyHandle = ObjectHandle
 type MdnsServiceHandle = ObjectHandle
 type DHTHandle = ObjectHandle
 type DiscoveryHandle = ObjectHandle
+type AdvertiserHandle = ObjectHandle
 type PeerChanHandle = ObjectHandle
 type MemberStoreHandle = ObjectHandle
 type PubSubHandle = ObjectHandle
 type TopicHandle = ObjectHandle
 type SubscriptionHandle = ObjectHandle
+type TopicEventHandlerHandle = ObjectHandle
+type ValidatorHandle = ObjectHandle
+type EventSubscriptionHandle = ObjectHandle
 
 type cancellableContext struct {
 	ctx    context.Context
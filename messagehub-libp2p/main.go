@@ -14,11 +14,7 @@ import (
 	"github.com/libp2p/go-libp2p-core/discovery"
 	"github.com/libp2p/go-libp2p-core/network"
 	"github.com/libp2p/go-libp2p-core/peer"
-	"github.com/libp2p/go-libp2p-kad-dht/dual"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
-	"github.com/libp2p/go-libp2p/p2p/discovery/routing"
-	mc "github.com/multiformats/go-multicodec"
-	mh "github.com/multiformats/go-multihash"
 )
 
 //export Alloc
@@ -76,7 +72,11 @@ func CreateHost(configJSON StringHandle, handle *HostHandle) StringHandle {
 func CloseHost(handle HostHandle) StringHandle {
 	hostNode := loadValue(handle).(*HostNode)
 	var errs []error
-	err := hostNode.ps.Close()
+	err := hostNode.networkStatus.close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("close network status: %w", err))
+	}
+	err = hostNode.ps.Close()
 	if err != nil {
 		errs = append(errs, fmt.Errorf("close ps: %w", err))
 	}
@@ -106,7 +106,8 @@ func GetHostID(handle HostHandle) StringHandle {
 func GetHostAddressInfo(hostHandle HostHandle, resultJSON *StringHandle) StringHandle {
 	*resultJSON = nil
 	host := loadValue(hostHandle).(*HostNode).host
-	addrs := host.Peerstore().Addrs(host.ID())
+	// Host addresses include external addresses mapped by UPnP/NAT-PMP.
+	addrs := uniqueAddrs(append(host.Peerstore().Addrs(host.ID()), host.Addrs()...))
 	addrInfo := peer.AddrInfo{
 		ID:    host.ID(),
 		Addrs: addrs,
@@ -119,6 +120,48 @@ func GetHostAddressInfo(hostHandle HostHandle, resultJSON *StringHandle) StringH
 	return nil
 }
 
+//export GetRelayServiceStatus
+func GetRelayServiceStatus(hostHandle HostHandle, resultJSON *StringHandle) StringHandle {
+	*resultJSON = nil
+	host := loadValue(hostHandle).(*HostNode).host
+	status := getRelayServiceStatus(host)
+	result, err := json.Marshal(status)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*resultJSON = C.CString(string(result))
+	return nil
+}
+
+//export GetNetworkStatus
+func GetNetworkStatus(hostHandle HostHandle, resultJSON *StringHandle) StringHandle {
+	*resultJSON = nil
+	hostNode := loadValue(hostHandle).(*HostNode)
+	status := hostNode.networkStatus.getStatus(hostNode.host)
+	result, err := json.Marshal(status)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*resultJSON = C.CString(string(result))
+	return nil
+}
+
+//export GetResourceUsage
+func GetResourceUsage(hostHandle HostHandle, resultJSON *StringHandle) StringHandle {
+	*resultJSON = nil
+	host := loadValue(hostHandle).(*HostNode).host
+	usage, err := getResourceUsage(host)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	result, err := json.Marshal(usage)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*resultJSON = C.CString(string(result))
+	return nil
+}
+
 //export GetIDFromAddressInfo
 func GetIDFromAddressInfo(addrInfo StringHandle, peerID *StringHandle) StringHandle {
 	*peerID = nil
@@ -195,11 +238,232 @@ func ProtectPeer(hostHandle HostHandle, peerID StringHandle, tag StringHandle) S
 	return nil
 }
 
+//export UnprotectPeer
+func UnprotectPeer(hostHandle HostHandle, peerID StringHandle, tag StringHandle) StringHandle {
+	host := loadValue(hostHandle).(*HostNode).host
+	p2pPeerID, err := peer.Decode(C.GoString(peerID))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	host.ConnManager().Unprotect(p2pPeerID, C.GoString(tag))
+	return nil
+}
+
+//export TagPeer
+func TagPeer(hostHandle HostHandle, peerID StringHandle, tag StringHandle, value int32) StringHandle {
+	host := loadValue(hostHandle).(*HostNode).host
+	p2pPeerID, err := peer.Decode(C.GoString(peerID))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	host.ConnManager().TagPeer(p2pPeerID, C.GoString(tag), int(value))
+	return nil
+}
+
+//export UntagPeer
+func UntagPeer(hostHandle HostHandle, peerID StringHandle, tag StringHandle) StringHandle {
+	host := loadValue(hostHandle).(*HostNode).host
+	p2pPeerID, err := peer.Decode(C.GoString(peerID))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	host.ConnManager().UntagPeer(p2pPeerID, C.GoString(tag))
+	return nil
+}
+
+//export GetPeerTags
+func GetPeerTags(hostHandle HostHandle, peerID StringHandle, resultJSON *StringHandle) StringHandle {
+	*resultJSON = nil
+	host := loadValue(hostHandle).(*HostNode).host
+	p2pPeerID, err := peer.Decode(C.GoString(peerID))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	result, err := json.Marshal(getPeerTags(host, p2pPeerID))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*resultJSON = C.CString(string(result))
+	return nil
+}
+
+//export BlockPeer
+func BlockPeer(hostHandle HostHandle, peerID StringHandle) StringHandle {
+	hostNode := loadValue(hostHandle).(*HostNode)
+	p2pPeerID, err := peer.Decode(C.GoString(peerID))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	err = blockPeer(hostNode.host, hostNode.gater, p2pPeerID)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	return nil
+}
+
+//export UnblockPeer
+func UnblockPeer(hostHandle HostHandle, peerID StringHandle) StringHandle {
+	hostNode := loadValue(hostHandle).(*HostNode)
+	p2pPeerID, err := peer.Decode(C.GoString(peerID))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	err = hostNode.gater.UnblockPeer(p2pPeerID)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	return nil
+}
+
+//export BlockSubnet
+func BlockSubnet(hostHandle HostHandle, cidr StringHandle) StringHandle {
+	hostNode := loadValue(hostHandle).(*HostNode)
+	err := blockSubnet(hostNode.host, hostNode.gater, C.GoString(cidr))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	return nil
+}
+
+//export UnblockSubnet
+func UnblockSubnet(hostHandle HostHandle, cidr StringHandle) StringHandle {
+	hostNode := loadValue(hostHandle).(*HostNode)
+	err := unblockSubnet(hostNode.gater, C.GoString(cidr))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	return nil
+}
+
+//export ListBlocked
+func ListBlocked(hostHandle HostHandle, resultJSON *StringHandle) StringHandle {
+	*resultJSON = nil
+	hostNode := loadValue(hostHandle).(*HostNode)
+	result, err := json.Marshal(getBlockList(hostNode.gater))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*resultJSON = C.CString(string(result))
+	return nil
+}
+
+//export AllowPeer
+func AllowPeer(hostHandle HostHandle, peerID StringHandle) StringHandle {
+	hostNode := loadValue(hostHandle).(*HostNode)
+	p2pPeerID, err := peer.Decode(C.GoString(peerID))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	err = hostNode.allowList.allowPeer(p2pPeerID)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	return nil
+}
+
+//export DisallowPeer
+func DisallowPeer(hostHandle HostHandle, peerID StringHandle) StringHandle {
+	hostNode := loadValue(hostHandle).(*HostNode)
+	p2pPeerID, err := peer.Decode(C.GoString(peerID))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	err = hostNode.allowList.disallowPeer(p2pPeerID)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	hostNode.allowList.closeDisallowed(hostNode.host)
+	return nil
+}
+
+//export AllowSubnet
+func AllowSubnet(hostHandle HostHandle, cidr StringHandle) StringHandle {
+	hostNode := loadValue(hostHandle).(*HostNode)
+	err := hostNode.allowList.allowSubnet(C.GoString(cidr))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	return nil
+}
+
+//export DisallowSubnet
+func DisallowSubnet(hostHandle HostHandle, cidr StringHandle) StringHandle {
+	hostNode := loadValue(hostHandle).(*HostNode)
+	err := hostNode.allowList.disallowSubnet(C.GoString(cidr))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	hostNode.allowList.closeDisallowed(hostNode.host)
+	return nil
+}
+
+//export ListAllowed
+func ListAllowed(hostHandle HostHandle, resultJSON *StringHandle) StringHandle {
+	*resultJSON = nil
+	hostNode := loadValue(hostHandle).(*HostNode)
+	result, err := json.Marshal(hostNode.allowList.list())
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*resultJSON = C.CString(string(result))
+	return nil
+}
+
+//export GetRateLimitCounters
+func GetRateLimitCounters(hostHandle HostHandle, resultJSON *StringHandle) StringHandle {
+	*resultJSON = nil
+	hostNode := loadValue(hostHandle).(*HostNode)
+	counters := make(map[string]rateLimitCounter)
+	if hostNode.rateLimiter != nil {
+		counters = hostNode.rateLimiter.getCounters()
+	}
+	result, err := json.Marshal(counters)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*resultJSON = C.CString(string(result))
+	return nil
+}
+
+//export GetPrivateNetworkKeyIDs
+func GetPrivateNetworkKeyIDs(hostHandle HostHandle, resultJSON *StringHandle) StringHandle {
+	*resultJSON = nil
+	hostNode := loadValue(hostHandle).(*HostNode)
+	keyIDs := make([]string, 0)
+	if hostNode.pnetAuth != nil {
+		keyIDs = hostNode.pnetAuth.getKeyIDs()
+	}
+	result, err := json.Marshal(keyIDs)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*resultJSON = C.CString(string(result))
+	return nil
+}
+
+//export GetPeerNetworkKeyID
+func GetPeerNetworkKeyID(hostHandle HostHandle, peerID StringHandle, keyID *StringHandle) StringHandle {
+	*keyID = nil
+	hostNode := loadValue(hostHandle).(*HostNode)
+	p2pPeerID, err := peer.Decode(C.GoString(peerID))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	if hostNode.pnetAuth == nil {
+		return nil
+	}
+	result := hostNode.pnetAuth.getPeerKeyID(p2pPeerID)
+	if result != "" {
+		*keyID = C.CString(result)
+	}
+	return nil
+}
+
 //export ConnectToSavedPeers
 func ConnectToSavedPeers(ctxHandle ContextHandle, hostHandle HostHandle) StringHandle {
 	ctx := loadValue(ctxHandle).(*cancellableContext).ctx
-	host := loadValue(hostHandle).(*HostNode).host
-	count := connectToSavedPeers(ctx, host)
+	hostNode := loadValue(hostHandle).(*HostNode)
+	count := connectToSavedPeers(ctx, hostNode.host, hostNode.addressScope)
 	return C.CString(fmt.Sprint(count))
 }
 
@@ -271,6 +535,9 @@ func StartMdnsService(mdnsServiceHandle MdnsServiceHandle) StringHandle {
 func StopMdnsService(mdnsServiceHandle MdnsServiceHandle) StringHandle {
 	service := loadValue(mdnsServiceHandle).(mdnsService)
 	service.cancel()
+	if service.emitter != nil {
+		service.emitter.Close()
+	}
 	err := service.service.Close()
 	if err != nil {
 		return C.CString(err.Error())
@@ -278,28 +545,64 @@ func StopMdnsService(mdnsServiceHandle MdnsServiceHandle) StringHandle {
 	return nil
 }
 
+//export CreateEventSubscription
+func CreateEventSubscription(hostHandle HostHandle, subscriptionHandle *EventSubscriptionHandle) StringHandle {
+	*subscriptionHandle = 0
+	host := loadValue(hostHandle).(*HostNode).host
+	subscription, err := newEventSubscription(host)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*subscriptionHandle = saveValue(subscription)
+	return nil
+}
+
+//export CancelEventSubscription
+func CancelEventSubscription(subscriptionHandle EventSubscriptionHandle) {
+	subscription := loadValue(subscriptionHandle).(*eventSubscription)
+	subscription.cancel()
+}
+
+//export GetNextEvent
+func GetNextEvent(ctxHandle ContextHandle, subscriptionHandle EventSubscriptionHandle, eventJSON *StringHandle) StringHandle {
+	*eventJSON = nil
+	ctx := loadValue(ctxHandle).(*cancellableContext).ctx
+	subscription := loadValue(subscriptionHandle).(*eventSubscription)
+	e, err := subscription.next(ctx)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	result, err := json.Marshal(e)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*eventJSON = C.CString(string(result))
+	return nil
+}
+
 //export CreateDHT
 func CreateDHT(ctxHandle ContextHandle, hostHandle HostHandle, configJSON StringHandle, dhtHandle *DHTHandle) StringHandle {
 	*dhtHandle = 0
 	ctx := loadValue(ctxHandle).(*cancellableContext).ctx
-	host := loadValue(hostHandle).(*HostNode).host
+	hostNode := loadValue(hostHandle).(*HostNode)
 	var config DHTConfig
 	err := json.Unmarshal([]byte(C.GoString(configJSON)), &config)
 	if err != nil {
 		return C.CString(fmt.Sprint("Error parsing JSON config: %w", err))
 	}
-	dualDHT, err := createDHT(ctx, host, config)
+	isPrivateNetwork := hostNode.pnetAuth != nil
+	dhtNode, err := createDHT(ctx, hostNode.host, config, hostNode.addressScope, isPrivateNetwork)
 	if err != nil {
 		return C.CString(err.Error())
 	}
-	*dhtHandle = saveValue(dualDHT)
+	*dhtHandle = saveValue(dhtNode)
 	return nil
 }
 
 //export CloseDHT
 func CloseDHT(handle DHTHandle) StringHandle {
-	dualDHT := loadValue(handle).(*dual.DHT)
-	err := dualDHT.Close()
+	dhtNode := loadValue(handle).(*DHTNode)
+	err := dhtNode.close()
 	if err != nil {
 		return C.CString(err.Error())
 	}
@@ -309,8 +612,8 @@ func CloseDHT(handle DHTHandle) StringHandle {
 //export BootstrapDHT
 func BootstrapDHT(ctxHandle ContextHandle, dhtHandle DHTHandle) StringHandle {
 	ctx := loadValue(ctxHandle).(*cancellableContext).ctx
-	dualDHT := loadValue(dhtHandle).(*dual.DHT)
-	err := dualDHT.Bootstrap(ctx)
+	dhtNode := loadValue(dhtHandle).(*DHTNode)
+	err := dhtNode.bootstrap(ctx)
 	if err != nil {
 		return C.CString(err.Error())
 	}
@@ -321,7 +624,7 @@ func BootstrapDHT(ctxHandle ContextHandle, dhtHandle DHTHandle) StringHandle {
 func FindPeer(ctxHandle ContextHandle, dhtHandle DHTHandle, peerID StringHandle, resultJSON *StringHandle) StringHandle {
 	*resultJSON = nil
 	ctx := loadValue(ctxHandle).(*cancellableContext).ctx
-	dualDHT := loadValue(dhtHandle).(*dual.DHT)
+	dualDHT := loadValue(dhtHandle).(*DHTNode).dht
 	p2pPeerID, err := peer.Decode(C.GoString(peerID))
 	if err != nil {
 		return C.CString(err.Error())
@@ -341,17 +644,79 @@ func FindPeer(ctxHandle ContextHandle, dhtHandle DHTHandle, peerID StringHandle,
 	return nil
 }
 
+//export PutValue
+func PutValue(ctxHandle ContextHandle, dhtHandle DHTHandle, key StringHandle, value StringHandle) StringHandle {
+	ctx := loadValue(ctxHandle).(*cancellableContext).ctx
+	dualDHT, err := loadValue(dhtHandle).(*DHTNode).getValueDHT(C.GoString(key))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	err = dualDHT.PutValue(ctx, C.GoString(key), []byte(C.GoString(value)))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	return nil
+}
+
+//export GetValue
+func GetValue(ctxHandle ContextHandle, dhtHandle DHTHandle, key StringHandle, value *StringHandle) StringHandle {
+	*value = nil
+	ctx := loadValue(ctxHandle).(*cancellableContext).ctx
+	dualDHT, err := loadValue(dhtHandle).(*DHTNode).getValueDHT(C.GoString(key))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	result, err := dualDHT.GetValue(ctx, C.GoString(key))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*value = C.CString(string(result))
+	return nil
+}
+
+// Plumbing for content addressed downloads, not used by the homeserver yet as its media IDs are not content IDs.
+// Files are only downloadable from providers when announced under the raw content ID of their whole data.
+//
+//export ProvideContent
+func ProvideContent(ctxHandle ContextHandle, dhtHandle DHTHandle, contentID StringHandle) StringHandle {
+	ctx := loadValue(ctxHandle).(*cancellableContext).ctx
+	dualDHT := loadValue(dhtHandle).(*DHTNode).dht
+	id, err := cid.Decode(C.GoString(contentID))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	err = dualDHT.Provide(ctx, id, true)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	return nil
+}
+
+//export FindProviders
+func FindProviders(ctxHandle ContextHandle, dhtHandle DHTHandle, contentID StringHandle, limit int32, result *PeerChanHandle) StringHandle {
+	*result = 0
+	ctx := loadValue(ctxHandle).(*cancellableContext).ctx
+	dualDHT := loadValue(dhtHandle).(*DHTNode).dht
+	id, err := cid.Decode(C.GoString(contentID))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	providers := dualDHT.FindProvidersAsync(ctx, id, int(limit))
+	*result = saveValue(providers)
+	return nil
+}
+
 //export FeedClosestPeersToAutoRelay
 func FeedClosestPeersToAutoRelay(ctxHandle ContextHandle, hostHandle HostHandle, dhtHandle DHTHandle) StringHandle {
 	ctx := loadValue(ctxHandle).(*cancellableContext).ctx
-	dualDHT := loadValue(dhtHandle).(*dual.DHT)
+	dualDHT := loadValue(dhtHandle).(*DHTNode).dht
 	hostNode := loadValue(hostHandle).(*HostNode)
 	peers, err := dualDHT.WAN.GetClosestPeers(ctx, hostNode.host.ID().String())
 	if err != nil {
 		return C.CString(err.Error())
 	}
 	for _, peerID := range peers {
-		addrs := hostNode.host.Peerstore().Addrs(peerID)
+		addrs := hostNode.addressScope.filterAddrs(hostNode.host.Peerstore().Addrs(peerID))
 		if len(addrs) > 0 {
 			addrInfo := peer.AddrInfo{
 				ID:    peerID,
@@ -367,16 +732,38 @@ func FeedClosestPeersToAutoRelay(ctxHandle ContextHandle, hostHandle HostHandle,
 }
 
 //export CreateDiscovery
-func CreateDiscovery(dhtHandle DHTHandle) DiscoveryHandle {
-	dualDHT := loadValue(dhtHandle).(*dual.DHT)
-	discovery := routing.NewRoutingDiscovery(dualDHT)
-	return saveValue(discovery)
+func CreateDiscovery(dhtHandle DHTHandle, result *DiscoveryHandle) StringHandle {
+	*result = 0
+	dualDHT := loadValue(dhtHandle).(*DHTNode).dht
+	discovery, err := createDiscovery(dualDHT)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*result = saveValue(discovery)
+	return nil
+}
+
+//export CreateRendezvousDiscovery
+func CreateRendezvousDiscovery(hostHandle HostHandle, rendezvousPointsJSON StringHandle, result *DiscoveryHandle) StringHandle {
+	*result = 0
+	hostNode := loadValue(hostHandle).(*HostNode)
+	var rendezvousPoints []string
+	err := json.Unmarshal([]byte(C.GoString(rendezvousPointsJSON)), &rendezvousPoints)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	discovery, err := createRendezvousDiscovery(hostNode.host, hostNode.addressScope, rendezvousPoints)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*result = saveValue(discovery)
+	return nil
 }
 
 //export Advertise
 func Advertise(ctxHandle ContextHandle, discoveryHandle DiscoveryHandle, topic StringHandle, ttl int32) StringHandle {
 	ctx := loadValue(ctxHandle).(*cancellableContext).ctx
-	d := loadValue(discoveryHandle).(*routing.RoutingDiscovery)
+	d := loadValue(discoveryHandle).(*peerDiscovery)
 	_, err := d.Advertise(ctx, C.GoString(topic), discovery.TTL(time.Duration(ttl)*time.Second))
 	if err != nil {
 		return C.CString(err.Error())
@@ -384,12 +771,63 @@ func Advertise(ctxHandle ContextHandle, discoveryHandle DiscoveryHandle, topic S
 	return nil
 }
 
+//export StartAdvertising
+func StartAdvertising(discoveryHandle DiscoveryHandle, topicsJSON StringHandle, ttl int32, result *AdvertiserHandle) StringHandle {
+	*result = 0
+	d := loadValue(discoveryHandle).(*peerDiscovery)
+	var topics []string
+	err := json.Unmarshal([]byte(C.GoString(topicsJSON)), &topics)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	a := newAdvertiser(d, topics, time.Duration(ttl)*time.Second)
+	*result = saveValue(a)
+	return nil
+}
+
+//export UpdateAdvertising
+func UpdateAdvertising(advertiserHandle AdvertiserHandle, topicsJSON StringHandle, ttl int32) StringHandle {
+	a := loadValue(advertiserHandle).(*advertiser)
+	var topics []string
+	err := json.Unmarshal([]byte(C.GoString(topicsJSON)), &topics)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	a.update(topics, time.Duration(ttl)*time.Second)
+	return nil
+}
+
+//export GetAdvertisingStatus
+func GetAdvertisingStatus(advertiserHandle AdvertiserHandle, resultJSON *StringHandle) StringHandle {
+	*resultJSON = nil
+	a := loadValue(advertiserHandle).(*advertiser)
+	result, err := json.Marshal(a.getStatus())
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*resultJSON = C.CString(string(result))
+	return nil
+}
+
+//export StopAdvertising
+func StopAdvertising(advertiserHandle AdvertiserHandle) {
+	a := loadValue(advertiserHandle).(*advertiser)
+	a.stop()
+}
+
 //export FindPeers
-func FindPeers(ctxHandle ContextHandle, discoveryHandle DiscoveryHandle, topic StringHandle, result *PeerChanHandle) StringHandle {
+func FindPeers(ctxHandle ContextHandle, discoveryHandle DiscoveryHandle, topic StringHandle, optionsJSON StringHandle, result *PeerChanHandle) StringHandle {
 	*result = 0
 	ctx := loadValue(ctxHandle).(*cancellableContext).ctx
-	discovery := loadValue(discoveryHandle).(*routing.RoutingDiscovery)
-	peers, err := discovery.FindPeers(ctx, C.GoString(topic))
+	discovery := loadValue(discoveryHandle).(*peerDiscovery)
+	var options FindPeersOptions
+	if optionsJSON != nil {
+		err := json.Unmarshal([]byte(C.GoString(optionsJSON)), &options)
+		if err != nil {
+			return C.CString(err.Error())
+		}
+	}
+	peers, err := discovery.findPeers(ctx, C.GoString(topic), options)
 	if err != nil {
 		return C.CString(err.Error())
 	}
@@ -455,24 +893,129 @@ func RemoveMember(memberStoreHandle MemberStoreHandle, topic StringHandle, peerI
 }
 
 //export CreatePubSub
-func CreatePubSub(ctxHandle ContextHandle, dhtHandle DHTHandle, memberStoreHandle MemberStoreHandle, pubsubHandle *PubSubHandle) StringHandle {
+func CreatePubSub(ctxHandle ContextHandle, dhtHandle DHTHandle, memberStoreHandle MemberStoreHandle, discoveryHandle DiscoveryHandle, configJSON StringHandle, pubsubHandle *PubSubHandle) StringHandle {
 	*pubsubHandle = 0
 	ctx := loadValue(ctxHandle).(*cancellableContext).ctx
-	dualDHT := loadValue(dhtHandle).(*dual.DHT)
+	dualDHT := loadValue(dhtHandle).(*DHTNode).dht
 	memberStore := loadValue(memberStoreHandle).(*MemberStore)
-	gossipSub, err := createPubSub(ctx, dualDHT, memberStore)
+	var d discovery.Discovery
+	if discoveryHandle != 0 {
+		d = loadValue(discoveryHandle).(*peerDiscovery)
+	}
+	var config PubSubConfig
+	err := json.Unmarshal([]byte(C.GoString(configJSON)), &config)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	node, err := createPubSub(ctx, dualDHT, memberStore, d, config)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*pubsubHandle = saveValue(node)
+	return nil
+}
+
+//export RegisterTopicValidator
+func RegisterTopicValidator(pubsubHandle PubSubHandle, topic StringHandle, validator StringHandle, externalValidatorHandle ValidatorHandle) StringHandle {
+	gossipSub := loadValue(pubsubHandle).(*PubSubNode).pubsub
+	var validators []pubsub.ValidatorEx
+	if validator != nil {
+		validatorFunc, err := getTopicValidator(C.GoString(validator))
+		if err != nil {
+			return C.CString(err.Error())
+		}
+		validators = append(validators, validatorFunc)
+	}
+	// The external validator runs last, so that it only sees messages passing the built-in checks.
+	if externalValidatorHandle != 0 {
+		externalValidator := loadValue(externalValidatorHandle).(*externalValidator)
+		validators = append(validators, externalValidator.validate)
+	}
+	if len(validators) == 0 {
+		return C.CString("no validator specified")
+	}
+	err := gossipSub.RegisterTopicValidator(C.GoString(topic), chainValidators(validators...))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	return nil
+}
+
+//export UnregisterTopicValidator
+func UnregisterTopicValidator(pubsubHandle PubSubHandle, topic StringHandle) StringHandle {
+	gossipSub := loadValue(pubsubHandle).(*PubSubNode).pubsub
+	err := gossipSub.UnregisterTopicValidator(C.GoString(topic))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	return nil
+}
+
+//export CreateExternalValidator
+func CreateExternalValidator(timeoutSeconds int32, validatorHandle *ValidatorHandle) StringHandle {
+	*validatorHandle = 0
+	validator, err := newExternalValidator(time.Duration(timeoutSeconds) * time.Second)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*validatorHandle = saveValue(validator)
+	return nil
+}
+
+//export CloseExternalValidator
+func CloseExternalValidator(validatorHandle ValidatorHandle) {
+	validator := loadValue(validatorHandle).(*externalValidator)
+	validator.close()
+}
+
+//export GetNextValidation
+func GetNextValidation(ctxHandle ContextHandle, validatorHandle ValidatorHandle, resultJSON *StringHandle) StringHandle {
+	*resultJSON = nil
+	ctx := loadValue(ctxHandle).(*cancellableContext).ctx
+	validator := loadValue(validatorHandle).(*externalValidator)
+	pending, err := validator.next(ctx)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	result, err := json.Marshal(pending)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*resultJSON = C.CString(string(result))
+	return nil
+}
+
+//export GetValidationDropCounters
+func GetValidationDropCounters(validatorHandle ValidatorHandle, resultJSON *StringHandle) StringHandle {
+	*resultJSON = nil
+	validator := loadValue(validatorHandle).(*externalValidator)
+	result, err := json.Marshal(validator.getDropCounters())
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*resultJSON = C.CString(string(result))
+	return nil
+}
+
+//export SetValidationResult
+func SetValidationResult(validatorHandle ValidatorHandle, id int64, result StringHandle) StringHandle {
+	validator := loadValue(validatorHandle).(*externalValidator)
+	validationResult, err := parseValidationResult(C.GoString(result))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	err = validator.setResult(id, validationResult)
 	if err != nil {
 		return C.CString(err.Error())
 	}
-	*pubsubHandle = saveValue(gossipSub)
 	return nil
 }
 
 //export JoinTopic
 func JoinTopic(pubsubHandle PubSubHandle, topic StringHandle, topicHandle *TopicHandle) StringHandle {
 	*topicHandle = 0
-	gossipSub := loadValue(pubsubHandle).(*pubsub.PubSub)
-	gossipTopic, err := gossipSub.Join(C.GoString(topic))
+	node := loadValue(pubsubHandle).(*PubSubNode)
+	gossipTopic, err := node.join(C.GoString(topic))
 	if err != nil {
 		return C.CString(err.Error())
 	}
@@ -480,6 +1023,22 @@ func JoinTopic(pubsubHandle PubSubHandle, topic StringHandle, topicHandle *Topic
 	return nil
 }
 
+//export GetPeerScores
+func GetPeerScores(pubsubHandle PubSubHandle, topic StringHandle, resultJSON *StringHandle) StringHandle {
+	*resultJSON = nil
+	node := loadValue(pubsubHandle).(*PubSubNode)
+	scores, err := node.getPeerScores(C.GoString(topic))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	result, err := json.Marshal(scores)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*resultJSON = C.CString(string(result))
+	return nil
+}
+
 //export CloseTopic
 func CloseTopic(topicHandle TopicHandle) StringHandle {
 	topic := loadValue(topicHandle).(*pubsub.Topic)
@@ -534,11 +1093,76 @@ func GetNextMessage(ctxHandle ContextHandle, subscriptionHandle SubscriptionHand
 	return nil
 }
 
+//export CreateTopicEventHandler
+func CreateTopicEventHandler(topicHandle TopicHandle, eventHandlerHandle *TopicEventHandlerHandle) StringHandle {
+	*eventHandlerHandle = 0
+	topic := loadValue(topicHandle).(*pubsub.Topic)
+	eventHandler, err := topic.EventHandler()
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*eventHandlerHandle = saveValue(eventHandler)
+	return nil
+}
+
+//export CancelTopicEventHandler
+func CancelTopicEventHandler(eventHandlerHandle TopicEventHandlerHandle) {
+	eventHandler := loadValue(eventHandlerHandle).(*pubsub.TopicEventHandler)
+	eventHandler.Cancel()
+}
+
+//export GetNextTopicEvent
+func GetNextTopicEvent(ctxHandle ContextHandle, eventHandlerHandle TopicEventHandlerHandle, eventJSON *StringHandle) StringHandle {
+	*eventJSON = nil
+	ctx := loadValue(ctxHandle).(*cancellableContext).ctx
+	eventHandler := loadValue(eventHandlerHandle).(*pubsub.TopicEventHandler)
+	e, err := nextTopicEvent(ctx, eventHandler)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	result, err := json.Marshal(e)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*eventJSON = C.CString(string(result))
+	return nil
+}
+
+//export ListTopicPeers
+func ListTopicPeers(topicHandle TopicHandle, resultJSON *StringHandle) StringHandle {
+	*resultJSON = nil
+	topic := loadValue(topicHandle).(*pubsub.Topic)
+	result, err := json.Marshal(listTopicPeers(topic))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	*resultJSON = C.CString(string(result))
+	return nil
+}
+
 //export DownloadFile
 func DownloadFile(ctxHandle ContextHandle, hostHandle HostHandle, peerID StringHandle, url StringHandle, filePath StringHandle) StringHandle {
 	ctx := loadValue(ctxHandle).(*cancellableContext).ctx
 	host := loadValue(hostHandle).(*HostNode).host
-	err := download(ctx, host, C.GoString(peerID), C.GoString(url), C.GoString(filePath))
+	err := download(ctx, host, C.GoString(peerID), C.GoString(url), C.GoString(filePath), cid.Undef)
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	return nil
+}
+
+// Plumbing for content addressed downloads, see ProvideContent.
+//
+//export DownloadFileFromProviders
+func DownloadFileFromProviders(ctxHandle ContextHandle, hostHandle HostHandle, dhtHandle DHTHandle, contentID StringHandle, url StringHandle, filePath StringHandle) StringHandle {
+	ctx := loadValue(ctxHandle).(*cancellableContext).ctx
+	host := loadValue(hostHandle).(*HostNode).host
+	dualDHT := loadValue(dhtHandle).(*DHTNode).dht
+	id, err := cid.Decode(C.GoString(contentID))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+	err = downloadFromProviders(ctx, host, dualDHT, id, C.GoString(url), C.GoString(filePath))
 	if err != nil {
 		return C.CString(err.Error())
 	}
@@ -552,13 +1176,7 @@ func EncodeEd25519PublicKey(hexPublicKey StringHandle, result *StringHandle) Str
 	if err != nil {
 		return C.CString(err.Error())
 	}
-	prefix := cid.Prefix{
-		Version:  1,
-		Codec:    uint64(mc.Ed25519Pub),
-		MhType:   mh.SHA2_256,
-		MhLength: -1,
-	}
-	id, err := prefix.Sum(publicKey)
+	id, err := encodeEd25519PublicKey(publicKey)
 	if err != nil {
 		return C.CString(err.Error())
 	}
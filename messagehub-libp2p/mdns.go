@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 
+	"github.com/libp2p/go-libp2p-core/event"
 	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p/p2p/discovery/mdns"
@@ -10,16 +11,21 @@ import (
 
 type mdnsService struct {
 	service mdns.Service
+	emitter event.Emitter
 	ctx     context.Context
 	cancel  context.CancelFunc
 }
 
 type mdnsNotifee struct {
-	host host.Host
-	ctx  context.Context
+	host    host.Host
+	emitter event.Emitter
+	ctx     context.Context
 }
 
 func (notifee mdnsNotifee) HandlePeerFound(addrInfo peer.AddrInfo) {
+	if notifee.emitter != nil {
+		notifee.emitter.Emit(EvtMdnsPeerFound{AddrInfo: addrInfo})
+	}
 	err := notifee.host.Connect(notifee.ctx, addrInfo)
 	if err != nil {
 		notifee.host.ConnManager().Protect(addrInfo.ID, "mdns")
@@ -28,13 +34,19 @@ func (notifee mdnsNotifee) HandlePeerFound(addrInfo peer.AddrInfo) {
 
 func newMdnsService(ctx context.Context, host host.Host, serviceName string) mdnsService {
 	ctx, cancel := context.WithCancel(ctx)
+	emitter, err := host.EventBus().Emitter(new(EvtMdnsPeerFound))
+	if err != nil {
+		emitter = nil
+	}
 	notifee := mdnsNotifee{
-		host: host,
-		ctx:  ctx,
+		host:    host,
+		emitter: emitter,
+		ctx:     ctx,
 	}
 	service := mdns.NewMdnsService(host, serviceName, notifee)
 	return mdnsService{
 		service: service,
+		emitter: emitter,
 		ctx:     ctx,
 		cancel:  cancel,
 	}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+)
+
+const (
+	// Publisher ID and sequence number, the gossipsub default.
+	defaultMessageID = "Default"
+	// Matrix event ID of the published PDU if the message is validly signed, falling back to the content hash.
+	eventMessageID = "EventID"
+	// Hash of the canonical JSON of the message.
+	contentHashMessageID = "ContentHash"
+)
+
+func getMessageIDFunction(name string) (pubsub.MsgIdFunction, error) {
+	switch name {
+	case defaultMessageID:
+		return nil, nil
+	case eventMessageID:
+		return eventMessageIDFunction, nil
+	case contentHashMessageID:
+		return contentHashMessageIDFunction, nil
+	default:
+		return nil, fmt.Errorf("unknown message ID function: %s", name)
+	}
+}
+
+// Falls back to hashing the raw data if the message is not valid JSON.
+func contentHashMessageIDFunction(msg *pb.Message) string {
+	data := msg.GetData()
+	if decoded, err := decodeJSON(data); err == nil {
+		if canonical, err := encodeCanonicalJSON(decoded); err == nil {
+			data = canonical
+		}
+	}
+	hash := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// Messages carrying exactly one PDU are identified by its event ID, see EventHash.TryGetEventId,
+// so that the same PDU published in different requests is only delivered once.
+// Pubsub marks the ID as seen before running validators, so the event ID is only used for messages
+// passing the checks of the Signature validator, with the event hash computed rather than taken from the PDU.
+// Anything else, such as a forged copy carrying the hash of a genuine event, falls back to the content hash
+// and can not suppress the genuine event.
+func eventMessageIDFunction(msg *pb.Message) string {
+	if request, err := verifyPublishedRequest(peer.ID(msg.GetFrom()), msg.GetData()); err == nil {
+		content, _ := request["content"].(map[string]any)
+		pdus, _ := content["pdus"].([]any)
+		if len(pdus) == 1 {
+			pdu, _ := pdus[0].(map[string]any)
+			if hash, ok := computeEventHash(pdu); ok {
+				return "$" + base64.RawURLEncoding.EncodeToString(hash)
+			}
+		}
+	}
+	return contentHashMessageIDFunction(msg)
+}
+
+// Returns the hash of the PDU if it matches the one it carries, see EventHash.ComputeHash.
+func computeEventHash(pdu map[string]any) ([]byte, bool) {
+	hashes, _ := pdu["hashes"].(map[string]any)
+	claimed, ok := hashes["sha256"].(string)
+	if !ok || len(hashes) != 1 {
+		return nil, false
+	}
+	redacted := make(map[string]any, len(pdu))
+	for key, value := range pdu {
+		switch key {
+		case "unsigned", "signatures", "hashes":
+		default:
+			redacted[key] = value
+		}
+	}
+	canonical, err := encodeCanonicalJSON(redacted)
+	if err != nil {
+		return nil, false
+	}
+	hash := sha256.Sum256(canonical)
+	if claimed != base64.RawStdEncoding.EncodeToString(hash[:]) {
+		return nil, false
+	}
+	return hash[:], true
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+)
+
+func createTestMessage(t *testing.T, from peer.ID, request map[string]any) *pb.Message {
+	t.Helper()
+	data, err := json.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pb.Message{Data: data, From: []byte(from)}
+}
+
+func getTestEventID(t *testing.T, pdu map[string]any) string {
+	t.Helper()
+	hash := pdu["hashes"].(map[string]any)["sha256"].(string)
+	data, err := base64.RawStdEncoding.DecodeString(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "$" + base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Copies a JSON object through its encoding, so that it can be modified independently.
+func copyJSON(t *testing.T, value map[string]any) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result map[string]any
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestEventMessageID(t *testing.T) {
+	now := time.Now().UnixMilli()
+	validUntil := now + time.Hour.Milliseconds()
+	peerID := createTestPeerID(t)
+	otherPeerID := createTestPeerID(t)
+	identity := createTestIdentity(t, validUntil, peerID)
+	other := createTestIdentity(t, validUntil, otherPeerID)
+	pdu := createSignedPDU(t, identity, "@alice:"+identity.id, now)
+	expected := getTestEventID(t, pdu)
+
+	id := eventMessageIDFunction(createTestMessage(t, peerID, createSignedRequest(t, identity, now, pdu)))
+	if id != expected {
+		t.Fatalf("expected %s, got %s", expected, id)
+	}
+	// The same PDU published in another request has the same ID.
+	request := createSignedRequest(t, other, now+1, pdu)
+	if id := eventMessageIDFunction(createTestMessage(t, otherPeerID, request)); id != expected {
+		t.Fatalf("expected %s, got %s", expected, id)
+	}
+}
+
+func TestEventMessageIDIgnoresForgedHash(t *testing.T) {
+	now := time.Now().UnixMilli()
+	validUntil := now + time.Hour.Milliseconds()
+	peerID := createTestPeerID(t)
+	identity := createTestIdentity(t, validUntil, peerID)
+	pdu := createSignedPDU(t, identity, "@alice:"+identity.id, now)
+	eventID := getTestEventID(t, pdu)
+
+	// A validly signed PDU claiming the hash of another event.
+	forged := createSignedPDU(t, identity, "@alice:"+identity.id, now)
+	forged["content"] = map[string]any{"body": "forged"}
+	forged["hashes"] = pdu["hashes"]
+	delete(forged, "signatures")
+	identity.signJSON(t, forged)
+	msg := createTestMessage(t, peerID, createSignedRequest(t, identity, now, forged))
+	if id := eventMessageIDFunction(msg); id == eventID || id != contentHashMessageIDFunction(msg) {
+		t.Fatalf("expected a forged hash to fall back to the content hash, got %s", id)
+	}
+
+	// A copy of the genuine PDU with its signatures forged.
+	copied := copyJSON(t, pdu)
+	copied["signatures"] = map[string]any{identity.id: map[string]any{serverKeyIdentifier: "forged"}}
+	msg = createTestMessage(t, peerID, createSignedRequest(t, identity, now, copied))
+	if id := eventMessageIDFunction(msg); id == eventID || id != contentHashMessageIDFunction(msg) {
+		t.Fatalf("expected an invalid copy to fall back to the content hash, got %s", id)
+	}
+
+	// A genuine PDU in an unsigned request.
+	request := createSignedRequest(t, identity, now, pdu)
+	delete(request, "signatures")
+	msg = createTestMessage(t, peerID, request)
+	if id := eventMessageIDFunction(msg); id == eventID || id != contentHashMessageIDFunction(msg) {
+		t.Fatalf("expected an unsigned request to fall back to the content hash, got %s", id)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+)
+
+const defaultPeerScoreInspectInterval = 10 * time.Second
+
+// Mirrors pubsub.TopicScoreParams, with durations in seconds.
+type TopicScoreConfig struct {
+	TopicWeight                            float64
+	TimeInMeshWeight                       float64
+	TimeInMeshQuantumSeconds               float64
+	TimeInMeshCap                          float64
+	FirstMessageDeliveriesWeight           float64
+	FirstMessageDeliveriesDecay            float64
+	FirstMessageDeliveriesCap              float64
+	MeshMessageDeliveriesWeight            float64
+	MeshMessageDeliveriesDecay             float64
+	MeshMessageDeliveriesCap               float64
+	MeshMessageDeliveriesThreshold         float64
+	MeshMessageDeliveriesWindowSeconds     float64
+	MeshMessageDeliveriesActivationSeconds float64
+	MeshFailurePenaltyWeight               float64
+	MeshFailurePenaltyDecay                float64
+	InvalidMessageDeliveriesWeight         float64
+	InvalidMessageDeliveriesDecay          float64
+}
+
+// Mirrors pubsub.PeerScoreParams, with durations in seconds.
+// Rooms are joined at runtime, so DefaultTopic applies to every joined topic not listed in Topics.
+type PeerScoreConfig struct {
+	Topics                      *map[string]TopicScoreConfig
+	DefaultTopic                *TopicScoreConfig
+	TopicScoreCap               float64
+	IPColocationFactorWeight    float64
+	IPColocationFactorThreshold int
+	IPColocationFactorWhitelist *[]string
+	BehaviourPenaltyWeight      float64
+	BehaviourPenaltyThreshold   float64
+	BehaviourPenaltyDecay       float64
+	DecayIntervalSeconds        *float64
+	DecayToZero                 *float64
+	RetainScoreSeconds          float64
+	Thresholds                  pubsub.PeerScoreThresholds
+	InspectIntervalSeconds      *float64
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
+
+func getTopicScoreParams(config TopicScoreConfig) *pubsub.TopicScoreParams {
+	return &pubsub.TopicScoreParams{
+		TopicWeight:                     config.TopicWeight,
+		TimeInMeshWeight:                config.TimeInMeshWeight,
+		TimeInMeshQuantum:               seconds(config.TimeInMeshQuantumSeconds),
+		TimeInMeshCap:                   config.TimeInMeshCap,
+		FirstMessageDeliveriesWeight:    config.FirstMessageDeliveriesWeight,
+		FirstMessageDeliveriesDecay:     config.FirstMessageDeliveriesDecay,
+		FirstMessageDeliveriesCap:       config.FirstMessageDeliveriesCap,
+		MeshMessageDeliveriesWeight:     config.MeshMessageDeliveriesWeight,
+		MeshMessageDeliveriesDecay:      config.MeshMessageDeliveriesDecay,
+		MeshMessageDeliveriesCap:        config.MeshMessageDeliveriesCap,
+		MeshMessageDeliveriesThreshold:  config.MeshMessageDeliveriesThreshold,
+		MeshMessageDeliveriesWindow:     seconds(config.MeshMessageDeliveriesWindowSeconds),
+		MeshMessageDeliveriesActivation: seconds(config.MeshMessageDeliveriesActivationSeconds),
+		MeshFailurePenaltyWeight:        config.MeshFailurePenaltyWeight,
+		MeshFailurePenaltyDecay:         config.MeshFailurePenaltyDecay,
+		InvalidMessageDeliveriesWeight:  config.InvalidMessageDeliveriesWeight,
+		InvalidMessageDeliveriesDecay:   config.InvalidMessageDeliveriesDecay,
+	}
+}
+
+func getPeerScoreParams(config PeerScoreConfig) (*pubsub.PeerScoreParams, error) {
+	params := &pubsub.PeerScoreParams{
+		Topics:                      make(map[string]*pubsub.TopicScoreParams),
+		TopicScoreCap:               config.TopicScoreCap,
+		AppSpecificScore:            func(peer.ID) float64 { return 0 },
+		IPColocationFactorWeight:    config.IPColocationFactorWeight,
+		IPColocationFactorThreshold: config.IPColocationFactorThreshold,
+		BehaviourPenaltyWeight:      config.BehaviourPenaltyWeight,
+		BehaviourPenaltyThreshold:   config.BehaviourPenaltyThreshold,
+		BehaviourPenaltyDecay:       config.BehaviourPenaltyDecay,
+		DecayInterval:               pubsub.DefaultDecayInterval,
+		DecayToZero:                 pubsub.DefaultDecayToZero,
+		RetainScore:                 seconds(config.RetainScoreSeconds),
+	}
+	if config.Topics != nil {
+		for topic, topicConfig := range *config.Topics {
+			params.Topics[topic] = getTopicScoreParams(topicConfig)
+		}
+	}
+	if config.IPColocationFactorWhitelist != nil {
+		for _, s := range *config.IPColocationFactorWhitelist {
+			_, ipNet, err := net.ParseCIDR(s)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing IP colocation whitelist: %w", err)
+			}
+			params.IPColocationFactorWhitelist = append(params.IPColocationFactorWhitelist, ipNet)
+		}
+	}
+	if config.DecayIntervalSeconds != nil {
+		params.DecayInterval = seconds(*config.DecayIntervalSeconds)
+	}
+	if config.DecayToZero != nil {
+		params.DecayToZero = *config.DecayToZero
+	}
+	return params, nil
+}
+
+type topicScore struct {
+	TimeInMeshSeconds        float64
+	FirstMessageDeliveries   float64
+	MeshMessageDeliveries    float64
+	InvalidMessageDeliveries float64
+}
+
+type peerScore struct {
+	Score              float64
+	AppSpecificScore   float64
+	IPColocationFactor float64
+	BehaviourPenalty   float64
+	Topics             map[string]topicScore
+}
+
+// Keeps the latest snapshot reported by the gossipsub score inspector.
+type peerScoreTracker struct {
+	mutex  sync.RWMutex
+	scores map[peer.ID]*pubsub.PeerScoreSnapshot
+}
+
+func (tracker *peerScoreTracker) inspect(scores map[peer.ID]*pubsub.PeerScoreSnapshot) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.scores = scores
+}
+
+// Returns scores of peers with statistics for the topic, or of all peers if the topic is empty.
+func (tracker *peerScoreTracker) getScores(topic string) map[string]peerScore {
+	tracker.mutex.RLock()
+	defer tracker.mutex.RUnlock()
+	result := make(map[string]peerScore)
+	for peerID, snapshot := range tracker.scores {
+		score := peerScore{
+			Score:              snapshot.Score,
+			AppSpecificScore:   snapshot.AppSpecificScore,
+			IPColocationFactor: snapshot.IPColocationFactor,
+			BehaviourPenalty:   snapshot.BehaviourPenalty,
+			Topics:             make(map[string]topicScore),
+		}
+		for name, topicSnapshot := range snapshot.Topics {
+			if topic != "" && name != topic {
+				continue
+			}
+			score.Topics[name] = topicScore{
+				TimeInMeshSeconds:        topicSnapshot.TimeInMesh.Seconds(),
+				FirstMessageDeliveries:   topicSnapshot.FirstMessageDeliveries,
+				MeshMessageDeliveries:    topicSnapshot.MeshMessageDeliveries,
+				InvalidMessageDeliveries: topicSnapshot.InvalidMessageDeliveries,
+			}
+		}
+		if topic != "" && len(score.Topics) == 0 {
+			continue
+		}
+		result[peer.Encode(peerID)] = score
+	}
+	return result
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/davidlazar/go-crypto/salsa20"
+	"github.com/libp2p/go-libp2p-core/connmgr"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/pnet"
+	"github.com/libp2p/go-libp2p-core/transport"
+	quic "github.com/libp2p/go-libp2p-quic-transport"
+	"github.com/libp2p/go-tcp-transport"
+	websocket "github.com/libp2p/go-ws-transport"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	mss "github.com/multiformats/go-multistream"
+	"golang.org/x/crypto/hkdf"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const privateNetworkAuthTimeout = 10 * time.Second
+
+type PrivateNetworkKey struct {
+	Secret  string
+	Version *int
+}
+
+type privateNetworkKey struct {
+	id      string
+	psk     pnet.PSK
+	authKey []byte
+}
+
+func readKey(secret []byte, salt []byte, info string) ([]byte, error) {
+	reader := hkdf.New(sha256.New, secret, salt, []byte(info))
+	key := make([]byte, 32)
+	_, err := io.ReadFull(reader, key)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Version 1 is the original derivation (nil salt, "libp2p" info) so that existing networks keep working.
+func derivePrivateNetworkKey(secret string, version int) (privateNetworkKey, error) {
+	var salt []byte
+	var pskInfo, authInfo string
+	switch version {
+	case 1:
+		pskInfo = "libp2p"
+		authInfo = "messagehub-pnet-auth"
+	case 2:
+		salt = []byte("messagehub-pnet")
+		pskInfo = "messagehub-pnet/v2/psk"
+		authInfo = "messagehub-pnet/v2/auth"
+	default:
+		return privateNetworkKey{}, fmt.Errorf("unsupported private network key version: %d", version)
+	}
+	psk, err := readKey([]byte(secret), salt, pskInfo)
+	if err != nil {
+		return privateNetworkKey{}, err
+	}
+	authKey, err := readKey([]byte(secret), salt, authInfo)
+	if err != nil {
+		return privateNetworkKey{}, err
+	}
+	// Identifies the key to users without revealing it.
+	idHash := hmac.New(sha256.New, authKey)
+	idHash.Write([]byte("key-id"))
+	id := hex.EncodeToString(idHash.Sum(nil)[:8])
+	return privateNetworkKey{
+		id:      id,
+		psk:     psk,
+		authKey: authKey,
+	}, nil
+}
+
+// The primary key comes first, followed by the accepted keys of a rotation.
+func getPrivateNetworkKeys(config HostConfig) ([]privateNetworkKey, error) {
+	secrets := []PrivateNetworkKey{{
+		Secret:  *config.PrivateNetworkSecret,
+		Version: config.PrivateNetworkKeyVersion,
+	}}
+	if config.AcceptedPrivateNetworkSecrets != nil {
+		secrets = append(secrets, *config.AcceptedPrivateNetworkSecrets...)
+	}
+	keys := make([]privateNetworkKey, 0, len(secrets))
+	for _, secret := range secrets {
+		version := 1
+		if secret.Version != nil {
+			version = *secret.Version
+		}
+		key, err := derivePrivateNetworkKey(secret.Secret, version)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Connections in a private network must prove knowledge of the primary key or one of the accepted keys
+// of a rotation, so nodes with different primary keys can still reach each other during a rotation.
+//
+// QUIC does not support PSK, so QUIC connections are authenticated by a transport wrapper
+// before they are handed to the swarm, and no protocol can be reached on an unauthenticated connection:
+// each side opens a stream and sends HMAC(key, sender ID || receiver ID) using its primary key,
+// connections that fail to prove knowledge of any accepted key in time are closed.
+// Peer IDs are already authenticated by the QUIC handshake, so the proof cannot be replayed by another peer.
+//
+// TCP and WebSocket connections are protected by the libp2p PSK protector, see privateNetworkConn.
+// Relayed connections go through the libp2p upgrader directly and only work with the primary key.
+type privateNetwork struct {
+	host     host.Host
+	keys     []privateNetworkKey
+	mutex    sync.Mutex
+	connKeys map[string]string
+}
+
+func newPrivateNetwork(keys []privateNetworkKey) *privateNetwork {
+	return &privateNetwork{
+		keys:     keys,
+		connKeys: make(map[string]string),
+	}
+}
+
+func (auth *privateNetwork) start(host host.Host) {
+	auth.host = host
+}
+
+func (auth *privateNetwork) getKeyIDs() []string {
+	result := make([]string, 0, len(auth.keys))
+	for _, key := range auth.keys {
+		result = append(result, key.id)
+	}
+	return result
+}
+
+// Connections are identified by their addresses, which the swarm reports unchanged.
+func getConnKey(addrs network.ConnMultiaddrs) string {
+	return addrs.LocalMultiaddr().String() + "|" + addrs.RemoteMultiaddr().String()
+}
+
+func (auth *privateNetwork) setConnKeyID(addrs network.ConnMultiaddrs, keyID string) {
+	auth.mutex.Lock()
+	defer auth.mutex.Unlock()
+	auth.connKeys[getConnKey(addrs)] = keyID
+}
+
+func (auth *privateNetwork) removeConnKeyID(addrs network.ConnMultiaddrs) {
+	auth.mutex.Lock()
+	defer auth.mutex.Unlock()
+	delete(auth.connKeys, getConnKey(addrs))
+}
+
+// Returns the ID of the key a connected peer used, or an empty string if unknown.
+func (auth *privateNetwork) getPeerKeyID(peerID peer.ID) string {
+	auth.mutex.Lock()
+	defer auth.mutex.Unlock()
+	for _, conn := range auth.host.Network().ConnsToPeer(peerID) {
+		if keyID, ok := auth.connKeys[getConnKey(conn)]; ok {
+			return keyID
+		}
+		// Relayed connections are protected by the upgrader with the primary key.
+		if _, err := conn.RemoteMultiaddr().ValueForProtocol(ma.P_CIRCUIT); err == nil {
+			return auth.keys[0].id
+		}
+	}
+	return ""
+}
+
+func computeMAC(key privateNetworkKey, sender peer.ID, receiver peer.ID) []byte {
+	mac := hmac.New(sha256.New, key.authKey)
+	mac.Write([]byte(sender))
+	mac.Write([]byte(receiver))
+	return mac.Sum(nil)
+}
+
+func (auth *privateNetwork) sendProof(ctx context.Context, conn transport.CapableConn) error {
+	s, err := conn.OpenStream(ctx)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		s.SetWriteDeadline(deadline)
+	}
+	_, err = s.Write(computeMAC(auth.keys[0], conn.LocalPeer(), conn.RemotePeer()))
+	if err != nil {
+		s.Reset()
+		return err
+	}
+	return s.Close()
+}
+
+// Returns the ID of the key the remote peer used.
+func (auth *privateNetwork) receiveProof(ctx context.Context, conn transport.CapableConn) (string, error) {
+	s, err := conn.AcceptStream()
+	if err != nil {
+		return "", err
+	}
+	defer s.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		s.SetReadDeadline(deadline)
+	}
+	received := make([]byte, sha256.Size)
+	_, err = io.ReadFull(s, received)
+	if err != nil {
+		s.Reset()
+		return "", err
+	}
+	for _, key := range auth.keys {
+		expected := computeMAC(key, conn.RemotePeer(), conn.LocalPeer())
+		if hmac.Equal(received, expected) {
+			return key.id, nil
+		}
+	}
+	return "", errors.New("invalid proof")
+}
+
+// Both sides send and receive a proof, regardless of which one dialed,
+// since either side may end up as the QUIC server after a hole punch.
+func (auth *privateNetwork) authenticate(ctx context.Context, conn transport.CapableConn) (transport.CapableConn, error) {
+	ctx, cancel := context.WithTimeout(ctx, privateNetworkAuthTimeout)
+	defer cancel()
+	// AcceptStream does not take a context, closing the connection unblocks it.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	sendErr := make(chan error, 1)
+	go func() {
+		sendErr <- auth.sendProof(ctx, conn)
+	}()
+	keyID, err := auth.receiveProof(ctx, conn)
+	if err == nil {
+		err = <-sendErr
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("private network authentication with %s failed: %w", conn.RemotePeer(), err)
+	}
+	auth.setConnKeyID(conn, keyID)
+	return &privateQuicConn{CapableConn: conn, auth: auth}, nil
+}
+
+// QUIC transport constructor that ignores the PSK, which QUIC does not support,
+// and authenticates connections instead.
+func (auth *privateNetwork) newQuicTransport(key crypto.PrivKey, gater connmgr.ConnectionGater, rcmgr network.ResourceManager) (transport.Transport, error) {
+	t, err := quic.NewTransport(key, nil, gater, rcmgr)
+	if err != nil {
+		return nil, err
+	}
+	return &privateQuicTransport{Transport: t, auth: auth}, nil
+}
+
+type privateQuicTransport struct {
+	transport.Transport
+	auth *privateNetwork
+}
+
+func (t *privateQuicTransport) Dial(ctx context.Context, raddr ma.Multiaddr, p peer.ID) (transport.CapableConn, error) {
+	conn, err := t.Transport.Dial(ctx, raddr, p)
+	if err != nil {
+		return nil, err
+	}
+	return t.auth.authenticate(ctx, conn)
+}
+
+func (t *privateQuicTransport) Listen(laddr ma.Multiaddr) (transport.Listener, error) {
+	l, err := t.Transport.Listen(laddr)
+	if err != nil {
+		return nil, err
+	}
+	listener := &privateQuicListener{
+		Listener: l,
+		auth:     t.auth,
+		conns:    make(chan transport.CapableConn),
+		done:     make(chan struct{}),
+	}
+	go listener.run()
+	return listener, nil
+}
+
+func (t *privateQuicTransport) Close() error {
+	if closer, ok := t.Transport.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Authenticates accepted connections concurrently, so a slow peer does not hold up the others.
+type privateQuicListener struct {
+	transport.Listener
+	auth  *privateNetwork
+	conns chan transport.CapableConn
+	done  chan struct{}
+	err   error
+}
+
+func (l *privateQuicListener) run() {
+	defer close(l.done)
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			l.err = err
+			return
+		}
+		go func() {
+			authenticated, err := l.auth.authenticate(context.Background(), conn)
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			select {
+			case l.conns <- authenticated:
+			case <-l.done:
+				authenticated.Close()
+			}
+		}()
+	}
+}
+
+func (l *privateQuicListener) Accept() (transport.CapableConn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, l.err
+	}
+}
+
+type privateQuicConn struct {
+	transport.CapableConn
+	auth *privateNetwork
+}
+
+func (conn *privateQuicConn) Close() error {
+	conn.auth.removeConnKeyID(conn)
+	return conn.CapableConn.Close()
+}
+
+// Size of the nonce sent by the libp2p PSK protector before the encrypted stream.
+const pskNonceSize = 24
+
+// Both sides of a connection start with the multistream header once the PSK protector is set up.
+var multistreamHeader = func() []byte {
+	header := protowire.AppendVarint(nil, uint64(len(mss.ProtocolID)+1))
+	header = append(header, mss.ProtocolID...)
+	return append(header, '\n')
+}()
+
+func newSalsa20(key privateNetworkKey, nonce []byte) cipher.Stream {
+	var psk [32]byte
+	copy(psk[:], key.psk)
+	return salsa20.New(&psk, nonce)
+}
+
+// Wraps a raw connection below the libp2p PSK protector, which only knows the primary key.
+// Each side encrypts with its own primary key, so outgoing data passes through unchanged,
+// while the key of incoming data is detected by decrypting the multistream header
+// with each accepted key, and incoming data is re-encrypted with the primary key if needed.
+type privateNetworkConn struct {
+	manet.Conn
+	auth   *privateNetwork
+	reader io.Reader
+}
+
+func (c *privateNetworkConn) Read(b []byte) (int, error) {
+	if c.reader == nil {
+		err := c.detectKey()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return c.reader.Read(b)
+}
+
+func (c *privateNetworkConn) detectKey() error {
+	header := make([]byte, pskNonceSize+len(multistreamHeader))
+	_, err := io.ReadFull(c.Conn, header)
+	if err != nil {
+		return err
+	}
+	nonce := header[:pskNonceSize]
+	plaintext := make([]byte, len(multistreamHeader))
+	for i, key := range c.auth.keys {
+		decrypt := newSalsa20(key, nonce)
+		decrypt.XORKeyStream(plaintext, header[pskNonceSize:])
+		if !bytes.Equal(plaintext, multistreamHeader) {
+			continue
+		}
+		c.auth.setConnKeyID(c.Conn, key.id)
+		if i == 0 {
+			c.reader = io.MultiReader(bytes.NewReader(header), c.Conn)
+			return nil
+		}
+		primaryNonce := make([]byte, pskNonceSize)
+		_, err := rand.Read(primaryNonce)
+		if err != nil {
+			return err
+		}
+		encrypt := newSalsa20(c.auth.keys[0], primaryNonce)
+		encrypt.XORKeyStream(plaintext, plaintext)
+		c.reader = io.MultiReader(
+			bytes.NewReader(append(primaryNonce, plaintext...)),
+			&recryptReader{reader: c.Conn, decrypt: decrypt, encrypt: encrypt})
+		return nil
+	}
+	return errors.New("private network key not accepted")
+}
+
+func (c *privateNetworkConn) Close() error {
+	c.auth.removeConnKeyID(c.Conn)
+	return c.Conn.Close()
+}
+
+type recryptReader struct {
+	reader  io.Reader
+	decrypt cipher.Stream
+	encrypt cipher.Stream
+}
+
+func (r *recryptReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	if n > 0 {
+		r.decrypt.XORKeyStream(b[:n], b[:n])
+		r.encrypt.XORKeyStream(b[:n], b[:n])
+	}
+	return n, err
+}
+
+type privateNetworkListener struct {
+	manet.Listener
+	auth *privateNetwork
+}
+
+func (l *privateNetworkListener) Accept() (manet.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &privateNetworkConn{Conn: conn, auth: l.auth}, nil
+}
+
+type privateNetworkUpgrader struct {
+	transport.Upgrader
+	auth *privateNetwork
+}
+
+func (u *privateNetworkUpgrader) UpgradeListener(t transport.Transport, l manet.Listener) transport.Listener {
+	return u.Upgrader.UpgradeListener(t, &privateNetworkListener{Listener: l, auth: u.auth})
+}
+
+func (u *privateNetworkUpgrader) Upgrade(ctx context.Context, t transport.Transport, maconn manet.Conn, dir network.Direction, p peer.ID, scope network.ConnManagementScope) (transport.CapableConn, error) {
+	return u.Upgrader.Upgrade(ctx, t, &privateNetworkConn{Conn: maconn, auth: u.auth}, dir, p, scope)
+}
+
+func (auth *privateNetwork) newTCPTransport(upgrader transport.Upgrader, rcmgr network.ResourceManager) (*tcp.TcpTransport, error) {
+	return tcp.NewTCPTransport(&privateNetworkUpgrader{Upgrader: upgrader, auth: auth}, rcmgr)
+}
+
+func (auth *privateNetwork) newWebSocketTransport(upgrader transport.Upgrader, rcmgr network.ResourceManager) *websocket.WebsocketTransport {
+	return websocket.New(&privateNetworkUpgrader{Upgrader: upgrader, auth: auth}, rcmgr)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/peerstore"
+)
+
+const testProtocol = "/messagehub/test/1.0.0"
+
+func createTestHost(t *testing.T, config HostConfig) *HostNode {
+	t.Helper()
+	config.DataPath = t.TempDir()
+	if config.ListenAddresses == nil {
+		config.ListenAddresses = &[]string{"/ip4/127.0.0.1/udp/0/quic"}
+	}
+	hostNode, err := createHost(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		hostNode.host.Close()
+		hostNode.networkStatus.close()
+		hostNode.ps.Close()
+		hostNode.ds.Close()
+	})
+	return hostNode
+}
+
+func openTestStream(from *HostNode, to *HostNode) (network.Stream, error) {
+	from.host.Peerstore().AddAddrs(to.host.ID(), to.host.Addrs(), peerstore.TempAddrTTL)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return from.host.NewStream(ctx, to.host.ID(), testProtocol)
+}
+
+func TestPrivateNetworkQuicRejectsUnknownKey(t *testing.T) {
+	secret := "secret"
+	otherSecret := "other secret"
+	server := createTestHost(t, HostConfig{PrivateNetworkSecret: &secret})
+	member := createTestHost(t, HostConfig{PrivateNetworkSecret: &secret})
+	outsider := createTestHost(t, HostConfig{PrivateNetworkSecret: &otherSecret})
+
+	handled := make(chan peer.ID, 2)
+	server.host.SetStreamHandler(testProtocol, func(s network.Stream) {
+		handled <- s.Conn().RemotePeer()
+		s.Close()
+	})
+
+	s, err := openTestStream(outsider, server)
+	if err == nil {
+		s.Reset()
+		t.Fatal("expected stream from a peer without the key to fail")
+	}
+	if server.host.Network().Connectedness(outsider.host.ID()) == network.Connected {
+		t.Fatal("expected no connection from a peer without the key")
+	}
+
+	s, err = openTestStream(member, server)
+	if err != nil {
+		t.Fatalf("error opening stream from a peer with the key: %v", err)
+	}
+	s.Close()
+	select {
+	case p := <-handled:
+		if p != member.host.ID() {
+			t.Fatalf("stream handled for unexpected peer %s", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream from a peer with the key was not handled")
+	}
+	select {
+	case p := <-handled:
+		t.Fatalf("unexpected stream handled for peer %s", p)
+	default:
+	}
+}
+
+func TestPrivateNetworkKeyRotation(t *testing.T) {
+	for _, listenAddress := range []string{"/ip4/127.0.0.1/tcp/0", "/ip4/127.0.0.1/tcp/0/ws", "/ip4/127.0.0.1/udp/0/quic"} {
+		t.Run(listenAddress, func(t *testing.T) {
+			oldSecret := "old secret"
+			newSecret := "new secret"
+			version := 2
+			listenAddresses := []string{listenAddress}
+			rotated := createTestHost(t, HostConfig{
+				PrivateNetworkSecret:          &newSecret,
+				PrivateNetworkKeyVersion:      &version,
+				AcceptedPrivateNetworkSecrets: &[]PrivateNetworkKey{{Secret: oldSecret}},
+				ListenAddresses:               &listenAddresses,
+				EnableWebSocket:               true,
+			})
+			pending := createTestHost(t, HostConfig{
+				PrivateNetworkSecret:          &oldSecret,
+				AcceptedPrivateNetworkSecrets: &[]PrivateNetworkKey{{Secret: newSecret, Version: &version}},
+				ListenAddresses:               &listenAddresses,
+				EnableWebSocket:               true,
+			})
+			newOnly := createTestHost(t, HostConfig{
+				PrivateNetworkSecret: &newSecret,
+				ListenAddresses:      &listenAddresses,
+				EnableWebSocket:      true,
+			})
+			for _, hostNode := range []*HostNode{rotated, pending, newOnly} {
+				hostNode.host.SetStreamHandler(testProtocol, func(s network.Stream) {
+					s.Close()
+				})
+			}
+			oldKeyID := pending.pnetAuth.keys[0].id
+			newKeyID := rotated.pnetAuth.keys[0].id
+
+			s, err := openTestStream(pending, rotated)
+			if err != nil {
+				t.Fatalf("error opening stream between rotated peers: %v", err)
+			}
+			s.Close()
+			if keyID := rotated.pnetAuth.getPeerKeyID(pending.host.ID()); keyID != oldKeyID {
+				t.Fatalf("expected key %s, got %q", oldKeyID, keyID)
+			}
+			if keyID := pending.pnetAuth.getPeerKeyID(rotated.host.ID()); keyID != newKeyID {
+				t.Fatalf("expected key %s, got %q", newKeyID, keyID)
+			}
+
+			s, err = openTestStream(newOnly, pending)
+			if err == nil {
+				s.Reset()
+				t.Fatal("expected stream from a peer without an accepted key to fail")
+			}
+		})
+	}
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"sync"
 
+	"github.com/libp2p/go-libp2p-core/discovery"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p-kad-dht/dual"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
@@ -72,12 +74,114 @@ func (store *MemberStore) filterPeer(pid peer.ID, topic string) bool {
 	return false
 }
 
-func createPubSub(ctx context.Context, dualDHT *dual.DHT, store *MemberStore) (*pubsub.PubSub, error) {
-	discovery := routing.NewRoutingDiscovery(dualDHT)
+type PubSubNode struct {
+	pubsub            *pubsub.PubSub
+	scores            *peerScoreTracker
+	scoredTopics      map[string]struct{}
+	defaultTopicScore *pubsub.TopicScoreParams
+}
+
+// Peers are discovered through the DHT unless another discovery is given.
+func createPubSub(ctx context.Context, dualDHT *dual.DHT, store *MemberStore, d discovery.Discovery, config PubSubConfig) (*PubSubNode, error) {
+	if d == nil {
+		d = routing.NewRoutingDiscovery(dualDHT)
+	}
 	options := []pubsub.Option{
-		pubsub.WithDiscovery(discovery),
+		pubsub.WithDiscovery(d),
 		pubsub.WithPeerFilter(store.filterPeer),
 	}
+	if config.MessageIDFunction != nil {
+		msgIDFunction, err := getMessageIDFunction(*config.MessageIDFunction)
+		if err != nil {
+			return nil, err
+		}
+		if msgIDFunction != nil {
+			options = append(options, pubsub.WithMessageIdFn(msgIDFunction))
+		}
+	}
+	node := &PubSubNode{
+		scoredTopics: make(map[string]struct{}),
+	}
+	if config.PeerScore != nil {
+		params, err := getPeerScoreParams(*config.PeerScore)
+		if err != nil {
+			return nil, err
+		}
+		for topic := range params.Topics {
+			node.scoredTopics[topic] = struct{}{}
+		}
+		if config.PeerScore.DefaultTopic != nil {
+			node.defaultTopicScore = getTopicScoreParams(*config.PeerScore.DefaultTopic)
+		}
+		inspectInterval := defaultPeerScoreInspectInterval
+		if config.PeerScore.InspectIntervalSeconds != nil {
+			inspectInterval = seconds(*config.PeerScore.InspectIntervalSeconds)
+		}
+		node.scores = &peerScoreTracker{}
+		thresholds := config.PeerScore.Thresholds
+		options = append(options,
+			pubsub.WithPeerScore(params, &thresholds),
+			// Must come after WithPeerScore.
+			pubsub.WithPeerScoreInspect(pubsub.ExtendedPeerScoreInspectFn(node.scores.inspect), inspectInterval))
+	}
 	gossipSub, err := pubsub.NewGossipSub(ctx, dualDHT.WAN.Host(), options...)
-	return gossipSub, err
+	if err != nil {
+		return nil, err
+	}
+	node.pubsub = gossipSub
+	return node, nil
+}
+
+// Applies the default topic score parameters to topics without their own.
+func (node *PubSubNode) join(topic string) (*pubsub.Topic, error) {
+	gossipTopic, err := node.pubsub.Join(topic)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := node.scoredTopics[topic]; !ok && node.defaultTopicScore != nil {
+		err = gossipTopic.SetScoreParams(node.defaultTopicScore)
+		if err != nil {
+			gossipTopic.Close()
+			return nil, err
+		}
+	}
+	return gossipTopic, nil
+}
+
+func (node *PubSubNode) getPeerScores(topic string) (map[string]peerScore, error) {
+	if node.scores == nil {
+		return nil, errors.New("peer scoring is not enabled")
+	}
+	return node.scores.getScores(topic), nil
+}
+
+type topicEvent struct {
+	Type   string
+	PeerID string
+}
+
+func nextTopicEvent(ctx context.Context, handler *pubsub.TopicEventHandler) (topicEvent, error) {
+	e, err := handler.NextPeerEvent(ctx)
+	if err != nil {
+		return topicEvent{}, err
+	}
+	result := topicEvent{
+		PeerID: peer.Encode(e.Peer),
+	}
+	switch e.Type {
+	case pubsub.PeerJoin:
+		result.Type = "PeerJoin"
+	case pubsub.PeerLeave:
+		result.Type = "PeerLeave"
+	}
+	return result, nil
+}
+
+func listTopicPeers(topic *pubsub.Topic) []string {
+	peers := topic.ListPeers()
+	result := make([]string, 0, len(peers))
+	for _, p := range peers {
+		result = append(result, peer.Encode(p))
+	}
+	return result
 }
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/control"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+const bucketSweepInterval = time.Minute
+
+type RateLimit struct {
+	// Tokens added per second.
+	Rate  float64
+	Burst int
+}
+
+type RateLimitSet struct {
+	PerIP     *RateLimit
+	PerSubnet *RateLimit
+	PerPeer   *RateLimit
+}
+
+type ConnectionRateLimitConfig struct {
+	Accept           *RateLimitSet
+	Dial             *RateLimitSet
+	SubnetPrefixIPv4 *int
+	SubnetPrefixIPv6 *int
+}
+
+type rateLimitCounter struct {
+	Allowed  uint64
+	Rejected uint64
+}
+
+type tokenBucket struct {
+	tokens   float64
+	lastTime time.Time
+}
+
+// Token buckets keyed by IP, subnet or peer ID.
+type bucketLimiter struct {
+	mutex     sync.Mutex
+	limit     RateLimit
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	counter   rateLimitCounter
+}
+
+func newBucketLimiter(limit *RateLimit, now time.Time) *bucketLimiter {
+	if limit == nil {
+		return nil
+	}
+	return &bucketLimiter{
+		limit:     *limit,
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: now,
+	}
+}
+
+func (limiter *bucketLimiter) refill(bucket *tokenBucket, now time.Time) {
+	elapsed := now.Sub(bucket.lastTime).Seconds()
+	bucket.tokens += elapsed * limiter.limit.Rate
+	if bucket.tokens > float64(limiter.limit.Burst) {
+		bucket.tokens = float64(limiter.limit.Burst)
+	}
+	bucket.lastTime = now
+}
+
+// Must be called with the limiter locked.
+func (limiter *bucketLimiter) getBucket(key string, now time.Time) *tokenBucket {
+	// Full buckets carry no state, drop them so the map does not grow unbounded.
+	if now.Sub(limiter.lastSweep) > bucketSweepInterval {
+		for k, bucket := range limiter.buckets {
+			limiter.refill(bucket, now)
+			if bucket.tokens >= float64(limiter.limit.Burst) {
+				delete(limiter.buckets, k)
+			}
+		}
+		limiter.lastSweep = now
+	}
+
+	bucket, ok := limiter.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{
+			tokens:   float64(limiter.limit.Burst),
+			lastTime: now,
+		}
+		limiter.buckets[key] = bucket
+	} else {
+		limiter.refill(bucket, now)
+	}
+	return bucket
+}
+
+type bucketKey struct {
+	limiter *bucketLimiter
+	key     string
+}
+
+// Takes a token from every bucket only if all of them have one,
+// so an attempt rejected by one limiter does not drain the others.
+func allowAll(now time.Time, keys ...bucketKey) bool {
+	buckets := make([]*tokenBucket, len(keys))
+	for i, k := range keys {
+		if k.limiter == nil {
+			continue
+		}
+		k.limiter.mutex.Lock()
+		defer k.limiter.mutex.Unlock()
+		buckets[i] = k.limiter.getBucket(k.key, now)
+	}
+	allowed := true
+	for i, k := range keys {
+		if buckets[i] != nil && buckets[i].tokens < 1 {
+			k.limiter.counter.Rejected += 1
+			allowed = false
+		}
+	}
+	if !allowed {
+		return false
+	}
+	for i, k := range keys {
+		if buckets[i] != nil {
+			buckets[i].tokens -= 1
+			k.limiter.counter.Allowed += 1
+		}
+	}
+	return true
+}
+
+func (limiter *bucketLimiter) allow(key string, now time.Time) bool {
+	return allowAll(now, bucketKey{limiter: limiter, key: key})
+}
+
+func (limiter *bucketLimiter) getCounter() rateLimitCounter {
+	if limiter == nil {
+		return rateLimitCounter{}
+	}
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	return limiter.counter
+}
+
+type rateLimitGater struct {
+	acceptPerIP      *bucketLimiter
+	acceptPerSubnet  *bucketLimiter
+	acceptPerPeer    *bucketLimiter
+	dialPerIP        *bucketLimiter
+	dialPerSubnet    *bucketLimiter
+	dialPerPeer      *bucketLimiter
+	subnetPrefixIPv4 int
+	subnetPrefixIPv6 int
+	// Replaced in tests to control token refills.
+	now func() time.Time
+}
+
+func newRateLimitGater(config ConnectionRateLimitConfig) (*rateLimitGater, error) {
+	gater := &rateLimitGater{
+		subnetPrefixIPv4: 24,
+		subnetPrefixIPv6: 48,
+		now:              time.Now,
+	}
+	now := gater.now()
+	if config.Accept != nil {
+		gater.acceptPerIP = newBucketLimiter(config.Accept.PerIP, now)
+		gater.acceptPerSubnet = newBucketLimiter(config.Accept.PerSubnet, now)
+		gater.acceptPerPeer = newBucketLimiter(config.Accept.PerPeer, now)
+	}
+	if config.Dial != nil {
+		gater.dialPerIP = newBucketLimiter(config.Dial.PerIP, now)
+		gater.dialPerSubnet = newBucketLimiter(config.Dial.PerSubnet, now)
+		gater.dialPerPeer = newBucketLimiter(config.Dial.PerPeer, now)
+	}
+	// net.CIDRMask returns nil for out of range prefixes, which would put every IP in the same subnet.
+	if config.SubnetPrefixIPv4 != nil {
+		if *config.SubnetPrefixIPv4 < 0 || *config.SubnetPrefixIPv4 > 32 {
+			return nil, fmt.Errorf("invalid IPv4 subnet prefix: %d", *config.SubnetPrefixIPv4)
+		}
+		gater.subnetPrefixIPv4 = *config.SubnetPrefixIPv4
+	}
+	if config.SubnetPrefixIPv6 != nil {
+		if *config.SubnetPrefixIPv6 < 0 || *config.SubnetPrefixIPv6 > 128 {
+			return nil, fmt.Errorf("invalid IPv6 subnet prefix: %d", *config.SubnetPrefixIPv6)
+		}
+		gater.subnetPrefixIPv6 = *config.SubnetPrefixIPv6
+	}
+	return gater, nil
+}
+
+func (gater *rateLimitGater) getSubnet(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		mask := net.CIDRMask(gater.subnetPrefixIPv4, 32)
+		return (&net.IPNet{IP: ip4.Mask(mask), Mask: mask}).String()
+	}
+	mask := net.CIDRMask(gater.subnetPrefixIPv6, 128)
+	return (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String()
+}
+
+// Addresses without an IP component (e.g. relayed) are not limited by IP or subnet.
+func (gater *rateLimitGater) allowAddr(addr ma.Multiaddr, perIP, perSubnet *bucketLimiter) bool {
+	ip, err := manet.ToIP(addr)
+	if err != nil {
+		return true
+	}
+	return allowAll(gater.now(),
+		bucketKey{limiter: perIP, key: ip.String()},
+		bucketKey{limiter: perSubnet, key: gater.getSubnet(ip)})
+}
+
+func (gater *rateLimitGater) InterceptPeerDial(p peer.ID) (allow bool) {
+	return gater.dialPerPeer.allow(string(p), gater.now())
+}
+
+func (gater *rateLimitGater) InterceptAddrDial(_ peer.ID, addr ma.Multiaddr) (allow bool) {
+	return gater.allowAddr(addr, gater.dialPerIP, gater.dialPerSubnet)
+}
+
+func (gater *rateLimitGater) InterceptAccept(addrs network.ConnMultiaddrs) (allow bool) {
+	return gater.allowAddr(addrs.RemoteMultiaddr(), gater.acceptPerIP, gater.acceptPerSubnet)
+}
+
+func (gater *rateLimitGater) InterceptSecured(direction network.Direction, p peer.ID, _ network.ConnMultiaddrs) (allow bool) {
+	if direction != network.DirInbound {
+		return true
+	}
+	return gater.acceptPerPeer.allow(string(p), gater.now())
+}
+
+func (gater *rateLimitGater) InterceptUpgraded(network.Conn) (allow bool, reason control.DisconnectReason) {
+	return true, 0
+}
+
+func (gater *rateLimitGater) getCounters() map[string]rateLimitCounter {
+	return map[string]rateLimitCounter{
+		"AcceptPerIP":     gater.acceptPerIP.getCounter(),
+		"AcceptPerSubnet": gater.acceptPerSubnet.getCounter(),
+		"AcceptPerPeer":   gater.acceptPerPeer.getCounter(),
+		"DialPerIP":       gater.dialPerIP.getCounter(),
+		"DialPerSubnet":   gater.dialPerSubnet.getCounter(),
+		"DialPerPeer":     gater.dialPerPeer.getCounter(),
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+type testConnAddrs struct {
+	remote ma.Multiaddr
+}
+
+func (addrs testConnAddrs) LocalMultiaddr() ma.Multiaddr {
+	return ma.StringCast("/ip4/127.0.0.1/tcp/4001")
+}
+
+func (addrs testConnAddrs) RemoteMultiaddr() ma.Multiaddr {
+	return addrs.remote
+}
+
+type testClock struct {
+	time time.Time
+}
+
+func (clock *testClock) now() time.Time {
+	return clock.time
+}
+
+func (clock *testClock) advance(d time.Duration) {
+	clock.time = clock.time.Add(d)
+}
+
+func createTestRateLimitGater(t *testing.T, config ConnectionRateLimitConfig) (*rateLimitGater, *testClock) {
+	t.Helper()
+	gater, err := newRateLimitGater(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &testClock{time: time.Now()}
+	gater.now = clock.now
+	return gater, clock
+}
+
+func acceptFrom(gater *rateLimitGater, ip string) bool {
+	return gater.InterceptAccept(testConnAddrs{remote: ma.StringCast("/ip4/" + ip + "/tcp/4001")})
+}
+
+func TestRateLimitPerPeer(t *testing.T) {
+	gater, _ := createTestRateLimitGater(t, ConnectionRateLimitConfig{
+		Accept: &RateLimitSet{PerPeer: &RateLimit{Rate: 0.001, Burst: 2}},
+	})
+	peerA := peer.ID("peer-a")
+	peerB := peer.ID("peer-b")
+	for i, expected := range []bool{true, true, false} {
+		if allowed := gater.InterceptSecured(network.DirInbound, peerA, nil); allowed != expected {
+			t.Fatalf("attempt %d: expected %v, got %v", i, expected, allowed)
+		}
+	}
+	if !gater.InterceptSecured(network.DirInbound, peerB, nil) {
+		t.Fatal("other peers should have their own bucket")
+	}
+	if !gater.InterceptSecured(network.DirOutbound, peerA, nil) {
+		t.Fatal("outbound connections should not be limited by the accept limits")
+	}
+	if !gater.InterceptPeerDial(peerA) {
+		t.Fatal("dials should not be limited without dial limits")
+	}
+	counter := gater.getCounters()["AcceptPerPeer"]
+	if counter.Allowed != 3 || counter.Rejected != 1 {
+		t.Fatalf("unexpected counter: %+v", counter)
+	}
+}
+
+func TestRateLimitPerSubnet(t *testing.T) {
+	gater, _ := createTestRateLimitGater(t, ConnectionRateLimitConfig{
+		Accept: &RateLimitSet{PerSubnet: &RateLimit{Rate: 0.001, Burst: 2}},
+	})
+	attempts := []struct {
+		ip       string
+		expected bool
+	}{
+		{"10.0.0.1", true},
+		{"10.0.0.2", true},
+		{"10.0.0.3", false},
+		{"10.0.1.1", true},
+	}
+	for _, attempt := range attempts {
+		if allowed := acceptFrom(gater, attempt.ip); allowed != attempt.expected {
+			t.Fatalf("%s: expected %v, got %v", attempt.ip, attempt.expected, allowed)
+		}
+	}
+	relayed := ma.StringCast("/p2p/QmcgpsyWgH8Y8ajJz1Cu72KnS5uo2Aa2LpzU7kinSupNKC/p2p-circuit")
+	if !gater.InterceptAccept(testConnAddrs{remote: relayed}) {
+		t.Fatal("addresses without an IP should not be limited")
+	}
+	counter := gater.getCounters()["AcceptPerSubnet"]
+	if counter.Allowed != 3 || counter.Rejected != 1 {
+		t.Fatalf("unexpected counter: %+v", counter)
+	}
+}
+
+func TestRateLimitRefill(t *testing.T) {
+	gater, clock := createTestRateLimitGater(t, ConnectionRateLimitConfig{
+		Accept: &RateLimitSet{PerIP: &RateLimit{Rate: 2, Burst: 3}},
+	})
+	expectAllowed := func(count int) {
+		t.Helper()
+		for i := 0; i < count; i++ {
+			if !acceptFrom(gater, "10.0.0.1") {
+				t.Fatalf("attempt %d should be allowed", i)
+			}
+		}
+		if acceptFrom(gater, "10.0.0.1") {
+			t.Fatalf("attempt %d should be rejected", count)
+		}
+	}
+	expectAllowed(3)
+	clock.advance(500 * time.Millisecond)
+	expectAllowed(1)
+	// Refills are capped at the burst size.
+	clock.advance(time.Minute)
+	expectAllowed(3)
+}
+
+func TestRateLimitRejectedAttemptKeepsOtherTokens(t *testing.T) {
+	gater, clock := createTestRateLimitGater(t, ConnectionRateLimitConfig{
+		Accept: &RateLimitSet{
+			PerIP:     &RateLimit{Rate: 1, Burst: 1},
+			PerSubnet: &RateLimit{Rate: 1, Burst: 2},
+		},
+	})
+	attempts := []struct {
+		ip       string
+		expected bool
+	}{
+		{"10.0.0.1", true},
+		// Rejected by the IP bucket, the subnet token must be kept.
+		{"10.0.0.1", false},
+		{"10.0.0.2", true},
+		// Rejected by the subnet bucket, the IP token must be kept.
+		{"10.0.0.3", false},
+	}
+	for i, attempt := range attempts {
+		if allowed := acceptFrom(gater, attempt.ip); allowed != attempt.expected {
+			t.Fatalf("attempt %d from %s: expected %v, got %v", i, attempt.ip, attempt.expected, allowed)
+		}
+	}
+	counters := gater.getCounters()
+	if counter := counters["AcceptPerIP"]; counter.Allowed != 2 || counter.Rejected != 1 {
+		t.Fatalf("unexpected IP counter: %+v", counter)
+	}
+	if counter := counters["AcceptPerSubnet"]; counter.Allowed != 2 || counter.Rejected != 1 {
+		t.Fatalf("unexpected subnet counter: %+v", counter)
+	}
+	// One second refills a single subnet token, and 10.0.0.3 still has its own.
+	clock.advance(time.Second)
+	if !acceptFrom(gater, "10.0.0.3") {
+		t.Fatal("IP bucket should not be drained by a rejected attempt")
+	}
+	if acceptFrom(gater, "10.0.0.1") {
+		t.Fatal("subnet bucket should be empty")
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/proto"
+	"github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/relay"
+)
+
+// Tag set by the relay service on peers holding a reservation.
+const relayReservationTag = "relay-reservation"
+
+type RelayServiceConfig struct {
+	ReservationTTLSeconds  *int64
+	MaxReservations        *int
+	MaxCircuits            *int
+	MaxReservationsPerPeer *int
+	MaxReservationsPerIP   *int
+	CircuitDurationSeconds *int64
+	CircuitDataLimit       *int64
+}
+
+type relayCircuitInfo struct {
+	PeerID string
+	Count  int
+}
+
+type relayServiceStatus struct {
+	Running      bool
+	Reservations []string
+	Circuits     []relayCircuitInfo
+}
+
+func getRelayResources(config RelayServiceConfig) relay.Resources {
+	resources := relay.DefaultResources()
+	if config.ReservationTTLSeconds != nil {
+		resources.ReservationTTL = time.Duration(*config.ReservationTTLSeconds) * time.Second
+	}
+	if config.MaxReservations != nil {
+		resources.MaxReservations = *config.MaxReservations
+	}
+	if config.MaxCircuits != nil {
+		resources.MaxCircuits = *config.MaxCircuits
+	}
+	if config.MaxReservationsPerPeer != nil {
+		resources.MaxReservationsPerPeer = *config.MaxReservationsPerPeer
+	}
+	if config.MaxReservationsPerIP != nil {
+		resources.MaxReservationsPerIP = *config.MaxReservationsPerIP
+	}
+	if config.CircuitDurationSeconds != nil {
+		resources.Limit.Duration = time.Duration(*config.CircuitDurationSeconds) * time.Second
+	}
+	if config.CircuitDataLimit != nil {
+		resources.Limit.Data = *config.CircuitDataLimit
+	}
+	return resources
+}
+
+// The relay service only runs while the host is publicly reachable,
+// active reservations and circuits are recovered from peer tags and open stop streams.
+func getRelayServiceStatus(host host.Host) relayServiceStatus {
+	status := relayServiceStatus{
+		Reservations: make([]string, 0),
+		Circuits:     make([]relayCircuitInfo, 0),
+	}
+	for _, protocol := range host.Mux().Protocols() {
+		if protocol == proto.ProtoIDv2Hop {
+			status.Running = true
+			break
+		}
+	}
+	for _, peerID := range host.Network().Peers() {
+		tagInfo := host.ConnManager().GetTagInfo(peerID)
+		if tagInfo == nil {
+			continue
+		}
+		if _, ok := tagInfo.Tags[relayReservationTag]; ok {
+			status.Reservations = append(status.Reservations, peer.Encode(peerID))
+		}
+	}
+	circuits := make(map[peer.ID]int)
+	for _, conn := range host.Network().Conns() {
+		for _, stream := range conn.GetStreams() {
+			if stream.Protocol() == proto.ProtoIDv2Stop && stream.Stat().Direction == network.DirOutbound {
+				circuits[conn.RemotePeer()] += 1
+			}
+		}
+	}
+	for peerID, count := range circuits {
+		status.Circuits = append(status.Circuits, relayCircuitInfo{
+			PeerID: peer.Encode(peerID),
+			Count:  count,
+		})
+	}
+	return status
+}
@@ -0,0 +1,730 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/discovery"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/peerstore"
+	"github.com/libp2p/go-libp2p-core/record"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Implements the libp2p rendezvous protocol:
+// https://github.com/libp2p/specs/blob/master/rendezvous/README.md
+const (
+	rendezvousProtocol          = "/rendezvous/1.0.0"
+	rendezvousTimeout           = time.Minute
+	maxRendezvousMessageSize    = 1 << 20
+	maxRendezvousNamespace      = 255
+	minRendezvousTTL            = 2 * time.Minute
+	defaultRendezvousTTL        = 2 * time.Hour
+	defaultMaxRendezvousTTL     = 72 * time.Hour
+	defaultRendezvousLimit      = 1000
+	defaultMaxRendezvousPerPeer = 1000
+	defaultMaxRendezvous        = 100000
+	rendezvousSweepInterval     = time.Minute
+)
+
+// Message types.
+const (
+	rendezvousRegister         = 0
+	rendezvousRegisterResponse = 1
+	rendezvousUnregister       = 2
+	rendezvousDiscover         = 3
+	rendezvousDiscoverResponse = 4
+)
+
+// Response message type expected for each request message type.
+var rendezvousResponseTypes = map[uint64]uint64{
+	rendezvousRegister: rendezvousRegisterResponse,
+	rendezvousDiscover: rendezvousDiscoverResponse,
+}
+
+// Response status codes.
+const (
+	rendezvousOK                      = 0
+	rendezvousInvalidNamespace        = 100
+	rendezvousInvalidSignedPeerRecord = 101
+	rendezvousInvalidTTL              = 102
+	rendezvousInvalidCookie           = 103
+	rendezvousNotAuthorized           = 200
+	rendezvousInternalError           = 300
+	rendezvousUnavailable             = 400
+)
+
+// Field numbers of the Message protobuf.
+const (
+	rendezvousMessageField              = 1
+	rendezvousRegisterField             = 2
+	rendezvousRegisterResponseField     = 3
+	rendezvousUnregisterField           = 4
+	rendezvousDiscoverField             = 5
+	rendezvousDiscoverResponseField     = 6
+	rendezvousDiscoverRegistrationField = 1
+)
+
+type rendezvousRegistration struct {
+	ns               string
+	signedPeerRecord []byte
+	ttl              uint64
+}
+
+type rendezvousResponse struct {
+	status        uint64
+	statusText    string
+	ttl           uint64
+	registrations []rendezvousRegistration
+	cookie        []byte
+}
+
+type rendezvousMessage struct {
+	messageType uint64
+	// Register and Unregister.
+	registration rendezvousRegistration
+	// Discover.
+	ns     string
+	limit  uint64
+	cookie []byte
+	// RegisterResponse and DiscoverResponse.
+	response rendezvousResponse
+}
+
+func appendStringField(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendBytesField(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendVarintField(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// Visits each field with its varint value or bytes, unknown wire types are skipped.
+func walkFields(b []byte, visit func(num protowire.Number, value uint64, data []byte)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		var value uint64
+		var data []byte
+		switch typ {
+		case protowire.VarintType:
+			value, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			data, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		visit(num, value, data)
+	}
+	return nil
+}
+
+func (r rendezvousRegistration) marshal() []byte {
+	var b []byte
+	b = appendStringField(b, 1, r.ns)
+	if r.signedPeerRecord != nil {
+		b = appendBytesField(b, 2, r.signedPeerRecord)
+	}
+	if r.ttl != 0 {
+		b = appendVarintField(b, 3, r.ttl)
+	}
+	return b
+}
+
+func unmarshalRendezvousRegistration(b []byte) (rendezvousRegistration, error) {
+	var r rendezvousRegistration
+	err := walkFields(b, func(num protowire.Number, value uint64, data []byte) {
+		switch num {
+		case 1:
+			r.ns = string(data)
+		case 2:
+			r.signedPeerRecord = data
+		case 3:
+			r.ttl = value
+		}
+	})
+	return r, err
+}
+
+func (m rendezvousMessage) marshal() []byte {
+	b := appendVarintField(nil, rendezvousMessageField, m.messageType)
+	var body []byte
+	var field protowire.Number
+	switch m.messageType {
+	case rendezvousRegister:
+		field = rendezvousRegisterField
+		body = m.registration.marshal()
+	case rendezvousUnregister:
+		field = rendezvousUnregisterField
+		body = appendStringField(nil, 1, m.registration.ns)
+	case rendezvousDiscover:
+		field = rendezvousDiscoverField
+		body = appendStringField(nil, 1, m.ns)
+		if m.limit != 0 {
+			body = appendVarintField(body, 2, m.limit)
+		}
+		if m.cookie != nil {
+			body = appendBytesField(body, 3, m.cookie)
+		}
+	case rendezvousRegisterResponse:
+		field = rendezvousRegisterResponseField
+		body = appendVarintField(nil, 1, m.response.status)
+		if m.response.statusText != "" {
+			body = appendStringField(body, 2, m.response.statusText)
+		}
+		if m.response.ttl != 0 {
+			body = appendVarintField(body, 3, m.response.ttl)
+		}
+	case rendezvousDiscoverResponse:
+		field = rendezvousDiscoverResponseField
+		for _, registration := range m.response.registrations {
+			body = appendBytesField(body, rendezvousDiscoverRegistrationField, registration.marshal())
+		}
+		if m.response.cookie != nil {
+			body = appendBytesField(body, 2, m.response.cookie)
+		}
+		body = appendVarintField(body, 3, m.response.status)
+		if m.response.statusText != "" {
+			body = appendStringField(body, 4, m.response.statusText)
+		}
+	}
+	return appendBytesField(b, field, body)
+}
+
+func unmarshalRendezvousMessage(b []byte) (rendezvousMessage, error) {
+	var m rendezvousMessage
+	var bodyErr error
+	err := walkFields(b, func(num protowire.Number, value uint64, data []byte) {
+		var err error
+		switch num {
+		case rendezvousMessageField:
+			m.messageType = value
+		case rendezvousRegisterField:
+			m.registration, err = unmarshalRendezvousRegistration(data)
+		case rendezvousUnregisterField:
+			err = walkFields(data, func(num protowire.Number, _ uint64, data []byte) {
+				if num == 1 {
+					m.registration.ns = string(data)
+				}
+			})
+		case rendezvousDiscoverField:
+			err = walkFields(data, func(num protowire.Number, value uint64, data []byte) {
+				switch num {
+				case 1:
+					m.ns = string(data)
+				case 2:
+					m.limit = value
+				case 3:
+					m.cookie = data
+				}
+			})
+		case rendezvousRegisterResponseField:
+			err = walkFields(data, func(num protowire.Number, value uint64, data []byte) {
+				switch num {
+				case 1:
+					m.response.status = value
+				case 2:
+					m.response.statusText = string(data)
+				case 3:
+					m.response.ttl = value
+				}
+			})
+		case rendezvousDiscoverResponseField:
+			err = walkFields(data, func(num protowire.Number, value uint64, data []byte) {
+				switch num {
+				case rendezvousDiscoverRegistrationField:
+					registration, err := unmarshalRendezvousRegistration(data)
+					if err != nil && bodyErr == nil {
+						bodyErr = err
+					}
+					m.response.registrations = append(m.response.registrations, registration)
+				case 2:
+					m.response.cookie = data
+				case 3:
+					m.response.status = value
+				case 4:
+					m.response.statusText = string(data)
+				}
+			})
+		}
+		if err != nil && bodyErr == nil {
+			bodyErr = err
+		}
+	})
+	if err != nil {
+		return m, err
+	}
+	return m, bodyErr
+}
+
+// Messages are prefixed with their unsigned varint length.
+func writeRendezvousMessage(w io.Writer, m rendezvousMessage) error {
+	body := m.marshal()
+	b := protowire.AppendVarint(make([]byte, 0, binary.MaxVarintLen64+len(body)), uint64(len(body)))
+	_, err := w.Write(append(b, body...))
+	return err
+}
+
+func readRendezvousMessage(r *bufio.Reader) (rendezvousMessage, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return rendezvousMessage{}, err
+	}
+	if length > maxRendezvousMessageSize {
+		return rendezvousMessage{}, errors.New("rendezvous message too large")
+	}
+	b := make([]byte, length)
+	_, err = io.ReadFull(r, b)
+	if err != nil {
+		return rendezvousMessage{}, err
+	}
+	return unmarshalRendezvousMessage(b)
+}
+
+// Returns the peer a signed peer record belongs to, checking that it was signed by that peer.
+func consumePeerRecord(signedPeerRecord []byte) (peer.AddrInfo, error) {
+	var peerRecord peer.PeerRecord
+	envelope, err := record.ConsumeTypedEnvelope(signedPeerRecord, &peerRecord)
+	if err != nil {
+		return peer.AddrInfo{}, err
+	}
+	signer, err := peer.IDFromPublicKey(envelope.PublicKey)
+	if err != nil {
+		return peer.AddrInfo{}, err
+	}
+	if signer != peerRecord.PeerID {
+		return peer.AddrInfo{}, errors.New("peer record not signed by its peer")
+	}
+	return peer.AddrInfo{
+		ID:    peerRecord.PeerID,
+		Addrs: peerRecord.Addrs,
+	}, nil
+}
+
+type RendezvousServiceConfig struct {
+	MaxTTLSeconds           *int
+	MaxRegistrations        *int
+	MaxRegistrationsPerPeer *int
+	MaxDiscoverLimit        *int
+}
+
+type rendezvousEntry struct {
+	signedPeerRecord []byte
+	expiry           time.Time
+	seq              uint64
+}
+
+// Keeps registrations in memory, they are refreshed by clients before they expire.
+type rendezvousService struct {
+	maxTTL           time.Duration
+	maxRegistrations int
+	maxPerPeer       int
+	maxLimit         int
+	mutex            sync.Mutex
+	seq              uint64
+	lastSweep        time.Time
+	count            int
+	peerCounts       map[peer.ID]int
+	registrations    map[string]map[peer.ID]*rendezvousEntry
+}
+
+func startRendezvousService(host host.Host, config RendezvousServiceConfig) {
+	service := &rendezvousService{
+		maxTTL:           defaultMaxRendezvousTTL,
+		maxRegistrations: defaultMaxRendezvous,
+		maxPerPeer:       defaultMaxRendezvousPerPeer,
+		maxLimit:         defaultRendezvousLimit,
+		peerCounts:       make(map[peer.ID]int),
+		registrations:    make(map[string]map[peer.ID]*rendezvousEntry),
+	}
+	if config.MaxTTLSeconds != nil {
+		service.maxTTL = time.Duration(*config.MaxTTLSeconds) * time.Second
+	}
+	if config.MaxRegistrations != nil {
+		service.maxRegistrations = *config.MaxRegistrations
+	}
+	if config.MaxRegistrationsPerPeer != nil {
+		service.maxPerPeer = *config.MaxRegistrationsPerPeer
+	}
+	if config.MaxDiscoverLimit != nil {
+		service.maxLimit = *config.MaxDiscoverLimit
+	}
+	host.SetStreamHandler(rendezvousProtocol, service.handleStream)
+}
+
+func (service *rendezvousService) handleStream(s network.Stream) {
+	defer s.Close()
+	remotePeer := s.Conn().RemotePeer()
+	reader := bufio.NewReader(s)
+	for {
+		s.SetDeadline(time.Now().Add(rendezvousTimeout))
+		request, err := readRendezvousMessage(reader)
+		if err != nil {
+			if err != io.EOF {
+				s.Reset()
+			}
+			return
+		}
+		var response rendezvousMessage
+		switch request.messageType {
+		case rendezvousRegister:
+			response = rendezvousMessage{
+				messageType: rendezvousRegisterResponse,
+				response:    service.register(remotePeer, request.registration),
+			}
+		case rendezvousUnregister:
+			service.unregister(remotePeer, request.registration.ns)
+			continue
+		case rendezvousDiscover:
+			response = rendezvousMessage{
+				messageType: rendezvousDiscoverResponse,
+				response:    service.discover(request.ns, request.limit, request.cookie),
+			}
+		default:
+			s.Reset()
+			return
+		}
+		err = writeRendezvousMessage(s, response)
+		if err != nil {
+			log.Printf("error writing rendezvous response to %s: %v", remotePeer, err)
+			s.Reset()
+			return
+		}
+	}
+}
+
+func (service *rendezvousService) removeEntry(ns string, peerID peer.ID) {
+	entries := service.registrations[ns]
+	if _, ok := entries[peerID]; !ok {
+		return
+	}
+	delete(entries, peerID)
+	if len(entries) == 0 {
+		delete(service.registrations, ns)
+	}
+	service.count -= 1
+	service.peerCounts[peerID] -= 1
+	if service.peerCounts[peerID] <= 0 {
+		delete(service.peerCounts, peerID)
+	}
+}
+
+func (service *rendezvousService) removeExpired(ns string, now time.Time) {
+	for peerID, entry := range service.registrations[ns] {
+		if now.After(entry.expiry) {
+			service.removeEntry(ns, peerID)
+		}
+	}
+}
+
+// Expired registrations of namespaces nobody registers to or discovers would otherwise
+// keep counting towards the limits forever.
+func (service *rendezvousService) sweepExpired(now time.Time) {
+	if now.Sub(service.lastSweep) < rendezvousSweepInterval {
+		return
+	}
+	for ns := range service.registrations {
+		service.removeExpired(ns, now)
+	}
+	service.lastSweep = now
+}
+
+func (service *rendezvousService) register(remotePeer peer.ID, registration rendezvousRegistration) rendezvousResponse {
+	if registration.ns == "" || len(registration.ns) > maxRendezvousNamespace {
+		return rendezvousResponse{status: rendezvousInvalidNamespace, statusText: "invalid namespace"}
+	}
+	ttl := defaultRendezvousTTL
+	if registration.ttl != 0 {
+		ttl = time.Duration(registration.ttl) * time.Second
+	}
+	if ttl < minRendezvousTTL || ttl > service.maxTTL {
+		return rendezvousResponse{status: rendezvousInvalidTTL, statusText: "invalid ttl"}
+	}
+	addrInfo, err := consumePeerRecord(registration.signedPeerRecord)
+	if err != nil {
+		return rendezvousResponse{status: rendezvousInvalidSignedPeerRecord, statusText: err.Error()}
+	}
+	if addrInfo.ID != remotePeer {
+		return rendezvousResponse{status: rendezvousNotAuthorized, statusText: "peer record does not match remote peer"}
+	}
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	now := time.Now()
+	service.removeExpired(registration.ns, now)
+	service.sweepExpired(now)
+	entries, ok := service.registrations[registration.ns]
+	if !ok {
+		entries = make(map[peer.ID]*rendezvousEntry)
+		service.registrations[registration.ns] = entries
+	}
+	if _, ok := entries[remotePeer]; !ok {
+		if service.count >= service.maxRegistrations || service.peerCounts[remotePeer] >= service.maxPerPeer {
+			return rendezvousResponse{status: rendezvousUnavailable, statusText: "too many registrations"}
+		}
+		service.count += 1
+		service.peerCounts[remotePeer] += 1
+	}
+	// Re-registration gets a new sequence number so that clients holding a cookie see the update.
+	service.seq += 1
+	entries[remotePeer] = &rendezvousEntry{
+		signedPeerRecord: registration.signedPeerRecord,
+		expiry:           now.Add(ttl),
+		seq:              service.seq,
+	}
+	return rendezvousResponse{status: rendezvousOK, ttl: uint64(ttl / time.Second)}
+}
+
+func (service *rendezvousService) unregister(remotePeer peer.ID, ns string) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	service.removeEntry(ns, remotePeer)
+}
+
+// The cookie holds the last returned sequence number followed by the namespace,
+// so that subsequent requests only return newer registrations.
+func (service *rendezvousService) discover(ns string, limit uint64, cookie []byte) rendezvousResponse {
+	if len(ns) > maxRendezvousNamespace {
+		return rendezvousResponse{status: rendezvousInvalidNamespace, statusText: "invalid namespace"}
+	}
+	var lastSeq uint64
+	if cookie != nil {
+		if len(cookie) < 8 || string(cookie[8:]) != ns {
+			return rendezvousResponse{status: rendezvousInvalidCookie, statusText: "invalid cookie"}
+		}
+		lastSeq = binary.BigEndian.Uint64(cookie)
+	}
+	if limit == 0 || limit > uint64(service.maxLimit) {
+		limit = uint64(service.maxLimit)
+	}
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	namespaces := []string{ns}
+	if ns == "" {
+		namespaces = make([]string, 0, len(service.registrations))
+		for name := range service.registrations {
+			namespaces = append(namespaces, name)
+		}
+	}
+	now := time.Now()
+	service.sweepExpired(now)
+	type candidate struct {
+		ns    string
+		entry *rendezvousEntry
+	}
+	candidates := make([]candidate, 0)
+	for _, name := range namespaces {
+		service.removeExpired(name, now)
+		for _, entry := range service.registrations[name] {
+			if entry.seq > lastSeq {
+				candidates = append(candidates, candidate{ns: name, entry: entry})
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].entry.seq < candidates[j].entry.seq
+	})
+	if uint64(len(candidates)) > limit {
+		candidates = candidates[:limit]
+	}
+	registrations := make([]rendezvousRegistration, 0, len(candidates))
+	for _, c := range candidates {
+		registrations = append(registrations, rendezvousRegistration{
+			ns:               c.ns,
+			signedPeerRecord: c.entry.signedPeerRecord,
+			ttl:              uint64(c.entry.expiry.Sub(now) / time.Second),
+		})
+		lastSeq = c.entry.seq
+	}
+	newCookie := make([]byte, 8, 8+len(ns))
+	binary.BigEndian.PutUint64(newCookie, lastSeq)
+	newCookie = append(newCookie, ns...)
+	return rendezvousResponse{
+		status:        rendezvousOK,
+		registrations: registrations,
+		cookie:        newCookie,
+	}
+}
+
+// A discovery.Discovery that registers with and queries rendezvous points.
+type rendezvousDiscovery struct {
+	host   host.Host
+	points []peer.AddrInfo
+}
+
+func newRendezvousDiscovery(host host.Host, scope AddressScope, rendezvousPoints []string) (*rendezvousDiscovery, error) {
+	if len(rendezvousPoints) == 0 {
+		return nil, errors.New("no rendezvous points")
+	}
+	points := make([]peer.AddrInfo, 0, len(rendezvousPoints))
+	for _, s := range rendezvousPoints {
+		addrInfo, err := peer.AddrInfoFromString(s)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing rendezvous point address: %w", err)
+		}
+		scopedAddrInfo := scope.filterAddrInfo(*addrInfo)
+		host.Peerstore().AddAddrs(scopedAddrInfo.ID, scopedAddrInfo.Addrs, peerstore.PermanentAddrTTL)
+		points = append(points, scopedAddrInfo)
+	}
+	return &rendezvousDiscovery{
+		host:   host,
+		points: points,
+	}, nil
+}
+
+func (d *rendezvousDiscovery) request(ctx context.Context, point peer.AddrInfo, request rendezvousMessage) (rendezvousResponse, error) {
+	err := d.host.Connect(ctx, point)
+	if err != nil {
+		return rendezvousResponse{}, err
+	}
+	s, err := d.host.NewStream(ctx, point.ID, rendezvousProtocol)
+	if err != nil {
+		return rendezvousResponse{}, err
+	}
+	defer s.Close()
+	s.SetDeadline(time.Now().Add(rendezvousTimeout))
+	err = writeRendezvousMessage(s, request)
+	if err != nil {
+		s.Reset()
+		return rendezvousResponse{}, err
+	}
+	response, err := readRendezvousMessage(bufio.NewReader(s))
+	if err != nil {
+		s.Reset()
+		return rendezvousResponse{}, err
+	}
+	if responseType, ok := rendezvousResponseTypes[request.messageType]; !ok || response.messageType != responseType {
+		return rendezvousResponse{}, fmt.Errorf("unexpected rendezvous response type %d for request type %d", response.messageType, request.messageType)
+	}
+	if response.response.status != rendezvousOK {
+		return rendezvousResponse{}, fmt.Errorf("rendezvous error %d: %s", response.response.status, response.response.statusText)
+	}
+	return response.response, nil
+}
+
+func (d *rendezvousDiscovery) signPeerRecord() ([]byte, error) {
+	peerRecord := peer.PeerRecordFromAddrInfo(peer.AddrInfo{
+		ID:    d.host.ID(),
+		Addrs: d.host.Addrs(),
+	})
+	envelope, err := record.Seal(peerRecord, d.host.Peerstore().PrivKey(d.host.ID()))
+	if err != nil {
+		return nil, err
+	}
+	return envelope.Marshal()
+}
+
+// Registers with every rendezvous point, succeeds if any of them accepts the registration.
+func (d *rendezvousDiscovery) Advertise(ctx context.Context, ns string, opts ...discovery.Option) (time.Duration, error) {
+	var options discovery.Options
+	err := options.Apply(opts...)
+	if err != nil {
+		return 0, err
+	}
+	ttl := options.Ttl
+	if ttl == 0 {
+		ttl = defaultRendezvousTTL
+	}
+	signedPeerRecord, err := d.signPeerRecord()
+	if err != nil {
+		return 0, err
+	}
+	request := rendezvousMessage{
+		messageType: rendezvousRegister,
+		registration: rendezvousRegistration{
+			ns:               ns,
+			signedPeerRecord: signedPeerRecord,
+			ttl:              uint64(ttl / time.Second),
+		},
+	}
+	var result time.Duration
+	var lastErr error
+	registered := false
+	for _, point := range d.points {
+		response, err := d.request(ctx, point, request)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		// The TTL is optional in responses, the requested one applies then.
+		granted := ttl
+		if response.ttl != 0 {
+			granted = time.Duration(response.ttl) * time.Second
+		}
+		if !registered || granted < result {
+			result = granted
+		}
+		registered = true
+	}
+	if !registered {
+		return 0, lastErr
+	}
+	return result, nil
+}
+
+func (d *rendezvousDiscovery) FindPeers(ctx context.Context, ns string, opts ...discovery.Option) (<-chan peer.AddrInfo, error) {
+	var options discovery.Options
+	err := options.Apply(opts...)
+	if err != nil {
+		return nil, err
+	}
+	request := rendezvousMessage{
+		messageType: rendezvousDiscover,
+		ns:          ns,
+		limit:       uint64(options.Limit),
+	}
+	result := make(chan peer.AddrInfo)
+	var wg sync.WaitGroup
+	for _, point := range d.points {
+		wg.Add(1)
+		go func(point peer.AddrInfo) {
+			defer wg.Done()
+			response, err := d.request(ctx, point, request)
+			if err != nil {
+				log.Printf("error discovering peers from rendezvous point %s: %v", point.ID, err)
+				return
+			}
+			for _, registration := range response.registrations {
+				addrInfo, err := consumePeerRecord(registration.signedPeerRecord)
+				if err != nil {
+					continue
+				}
+				select {
+				case result <- addrInfo:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(point)
+	}
+	go func() {
+		wg.Wait()
+		close(result)
+	}()
+	return result, nil
+}
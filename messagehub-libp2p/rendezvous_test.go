@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/discovery"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/record"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestRendezvousMessageRoundTrip(t *testing.T) {
+	registration := rendezvousRegistration{
+		ns:               "room",
+		signedPeerRecord: []byte("record"),
+		ttl:              7200,
+	}
+	messages := []rendezvousMessage{
+		{messageType: rendezvousRegister, registration: registration},
+		{messageType: rendezvousUnregister, registration: rendezvousRegistration{ns: "room"}},
+		{messageType: rendezvousDiscover, ns: "room", limit: 10, cookie: []byte("cookie")},
+		{messageType: rendezvousDiscover, ns: "room"},
+		{messageType: rendezvousRegisterResponse, response: rendezvousResponse{status: rendezvousOK, ttl: 3600}},
+		{messageType: rendezvousRegisterResponse, response: rendezvousResponse{status: rendezvousInvalidTTL, statusText: "invalid ttl"}},
+		{messageType: rendezvousDiscoverResponse, response: rendezvousResponse{
+			status:        rendezvousOK,
+			registrations: []rendezvousRegistration{registration, {ns: "other", signedPeerRecord: []byte("other record")}},
+			cookie:        []byte("cookie"),
+		}},
+	}
+	var buffer bytes.Buffer
+	for _, m := range messages {
+		err := writeRendezvousMessage(&buffer, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	reader := bufio.NewReader(&buffer)
+	for _, expected := range messages {
+		m, err := readRendezvousMessage(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, expected) {
+			t.Fatalf("expected %+v, got %+v", expected, m)
+		}
+	}
+}
+
+func createSignedPeerRecord(t *testing.T) (peer.ID, []byte) {
+	t.Helper()
+	privateKey, _, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerID, err := peer.IDFromPrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerRecord := peer.PeerRecordFromAddrInfo(peer.AddrInfo{
+		ID:    peerID,
+		Addrs: []ma.Multiaddr{ma.StringCast("/ip4/127.0.0.1/udp/4001/quic")},
+	})
+	envelope, err := record.Seal(peerRecord, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedPeerRecord, err := envelope.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return peerID, signedPeerRecord
+}
+
+func TestRendezvousExpiredRegistrationsFreeLimits(t *testing.T) {
+	service := &rendezvousService{
+		maxTTL:           defaultMaxRendezvousTTL,
+		maxRegistrations: 1,
+		maxPerPeer:       1,
+		maxLimit:         defaultRendezvousLimit,
+		lastSweep:        time.Now(),
+		peerCounts:       make(map[peer.ID]int),
+		registrations:    make(map[string]map[peer.ID]*rendezvousEntry),
+	}
+	peerID, signedPeerRecord := createSignedPeerRecord(t)
+	response := service.register(peerID, rendezvousRegistration{ns: "expired", signedPeerRecord: signedPeerRecord})
+	if response.status != rendezvousOK {
+		t.Fatalf("registration failed: %s", response.statusText)
+	}
+	response = service.register(peerID, rendezvousRegistration{ns: "room", signedPeerRecord: signedPeerRecord})
+	if response.status != rendezvousUnavailable {
+		t.Fatalf("expected registration over the limit to fail, got status %d", response.status)
+	}
+
+	// Nobody touches the namespace after its registration expires.
+	service.registrations["expired"][peerID].expiry = time.Now().Add(-time.Second)
+	service.lastSweep = time.Now().Add(-rendezvousSweepInterval)
+	response = service.register(peerID, rendezvousRegistration{ns: "room", signedPeerRecord: signedPeerRecord})
+	if response.status != rendezvousOK {
+		t.Fatalf("registration failed: %s", response.statusText)
+	}
+	if service.count != 1 || service.peerCounts[peerID] != 1 {
+		t.Fatalf("unexpected counts: %d, %d", service.count, service.peerCounts[peerID])
+	}
+	if _, ok := service.registrations["expired"]; ok {
+		t.Fatal("expected expired namespace to be removed")
+	}
+}
+
+func TestRendezvousAdvertiseWithoutGrantedTTL(t *testing.T) {
+	server := createTestHost(t, HostConfig{})
+	client := createTestHost(t, HostConfig{})
+	// Responds OK without a TTL, which is optional.
+	server.host.SetStreamHandler(rendezvousProtocol, func(s network.Stream) {
+		defer s.Close()
+		_, err := readRendezvousMessage(bufio.NewReader(s))
+		if err != nil {
+			s.Reset()
+			return
+		}
+		writeRendezvousMessage(s, rendezvousMessage{messageType: rendezvousRegisterResponse})
+	})
+	serverAddrInfo := peer.AddrInfo{ID: server.host.ID(), Addrs: server.host.Addrs()}
+	d, err := newRendezvousDiscovery(client.host, AddressScopeAny, []string{getP2pAddr(t, serverAddrInfo)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ttl, err := d.Advertise(context.Background(), "room", discovery.TTL(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl != time.Hour {
+		t.Fatalf("expected the requested TTL, got %v", ttl)
+	}
+}
+
+func TestRendezvousRejectsMismatchedResponseType(t *testing.T) {
+	server := createTestHost(t, HostConfig{})
+	client := createTestHost(t, HostConfig{})
+	// Answers every request with the response type of the other request.
+	server.host.SetStreamHandler(rendezvousProtocol, func(s network.Stream) {
+		defer s.Close()
+		request, err := readRendezvousMessage(bufio.NewReader(s))
+		if err != nil {
+			s.Reset()
+			return
+		}
+		responseType := uint64(rendezvousRegisterResponse)
+		if request.messageType == rendezvousRegister {
+			responseType = rendezvousDiscoverResponse
+		}
+		writeRendezvousMessage(s, rendezvousMessage{messageType: responseType})
+	})
+	serverAddrInfo := peer.AddrInfo{ID: server.host.ID(), Addrs: server.host.Addrs()}
+	d, err := newRendezvousDiscovery(client.host, AddressScopeAny, []string{getP2pAddr(t, serverAddrInfo)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := []rendezvousMessage{
+		{messageType: rendezvousRegister, registration: rendezvousRegistration{ns: "room"}},
+		{messageType: rendezvousDiscover, ns: "room"},
+	}
+	for _, request := range requests {
+		_, err = d.request(context.Background(), serverAddrInfo, request)
+		if err == nil {
+			t.Fatalf("expected an error for request type %d", request.messageType)
+		}
+	}
+}
+
+func getP2pAddr(t *testing.T, addrInfo peer.AddrInfo) string {
+	t.Helper()
+	addrs, err := peer.AddrInfoToP2pAddrs(&addrInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addrs[0].String()
+}
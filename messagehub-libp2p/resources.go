@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	rcmgr "github.com/libp2p/go-libp2p-resource-manager"
+)
+
+// Limits use the JSON format of go-libp2p-resource-manager,
+// anything not specified falls back to the default limits.
+func createResourceManager(limitsJSON json.RawMessage) (network.ResourceManager, error) {
+	limiter, err := rcmgr.NewDefaultLimiterFromJSON(bytes.NewReader(limitsJSON))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing resource limits: %w", err)
+	}
+	libp2p.SetDefaultServiceLimits(limiter)
+	return rcmgr.NewResourceManager(limiter)
+}
+
+func getResourceUsage(host host.Host) (*rcmgr.ResourceManagerStat, error) {
+	state, ok := host.Network().ResourceManager().(rcmgr.ResourceManagerState)
+	if !ok {
+		return nil, fmt.Errorf("resource manager does not report usage")
+	}
+	stat := state.Stat()
+	return &stat, nil
+}
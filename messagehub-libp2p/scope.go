@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// Restricts the addresses the host talks to, independent of PrivateNetworkSecret.
+type AddressScope string
+
+const (
+	AddressScopeAny     AddressScope = "any"
+	AddressScopePrivate AddressScope = "private"
+	AddressScopePublic  AddressScope = "public"
+)
+
+// Defaults to private addresses only in private network mode, for compatibility.
+func getAddressScope(config HostConfig) (AddressScope, error) {
+	if config.AddressScope == nil {
+		if config.PrivateNetworkSecret != nil {
+			return AddressScopePrivate, nil
+		}
+		return AddressScopeAny, nil
+	}
+	scope := AddressScope(*config.AddressScope)
+	switch scope {
+	case AddressScopeAny, AddressScopePrivate, AddressScopePublic:
+		return scope, nil
+	}
+	return "", fmt.Errorf("invalid address scope: %s", scope)
+}
+
+func (scope AddressScope) allows(addr ma.Multiaddr) bool {
+	switch scope {
+	case AddressScopePrivate:
+		return manet.IsPrivateAddr(addr)
+	case AddressScopePublic:
+		return manet.IsPublicAddr(addr)
+	}
+	return true
+}
+
+func (scope AddressScope) filterAddrs(addrs []ma.Multiaddr) []ma.Multiaddr {
+	return ma.FilterAddrs(addrs, scope.allows)
+}
+
+func (scope AddressScope) filterAddrInfo(addrInfo peer.AddrInfo) peer.AddrInfo {
+	return peer.AddrInfo{
+		ID:    addrInfo.ID,
+		Addrs: scope.filterAddrs(addrInfo.Addrs),
+	}
+}
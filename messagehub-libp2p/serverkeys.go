@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	record "github.com/libp2p/go-libp2p-record"
+	mc "github.com/multiformats/go-multicodec"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// DHT namespace of server_keys records, keyed by "/server_keys/<encoded Ed25519 public key>".
+const serverKeysNamespace = "server_keys"
+
+// Key identifier of the server key signature, see LocalIdentity.ServerKeyIdentifier.
+const serverKeyIdentifier = "ed25519:ID"
+
+func encodeEd25519PublicKey(publicKey []byte) (cid.Cid, error) {
+	prefix := cid.Prefix{
+		Version:  1,
+		Codec:    uint64(mc.Ed25519Pub),
+		MhType:   mh.SHA2_256,
+		MhLength: -1,
+	}
+	return prefix.Sum(publicKey)
+}
+
+type serverKeysValidator struct{}
+
+// Verifies the self signature of server keys, returns the encoded identity ID and valid_until_ts.
+func verifyServerKeysObject(serverKeys map[string]any) (string, int64, error) {
+	serverName, ok := serverKeys["server_name"].(string)
+	if !ok {
+		return "", 0, errors.New("invalid server_name")
+	}
+	publicKey, err := base64.RawStdEncoding.DecodeString(serverName)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return "", 0, errors.New("invalid server_name")
+	}
+	id, err := encodeEd25519PublicKey(publicKey)
+	if err != nil {
+		return "", 0, err
+	}
+
+	signatures, ok := serverKeys["signatures"].(map[string]any)
+	if !ok {
+		return "", 0, errors.New("invalid signatures")
+	}
+	serverSignatures, ok := signatures[serverName].(map[string]any)
+	if !ok {
+		return "", 0, errors.New("server signature not found")
+	}
+	encodedSignature, ok := serverSignatures[serverKeyIdentifier].(string)
+	if !ok {
+		return "", 0, errors.New("server signature not found")
+	}
+	signature, err := base64.RawStdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid signature: %w", err)
+	}
+	data, err := encodeCanonicalJSON(withoutKeys(serverKeys, "signatures"))
+	if err != nil {
+		return "", 0, err
+	}
+	if !ed25519.Verify(publicKey, data, signature) {
+		return "", 0, errors.New("invalid signature")
+	}
+
+	validUntil, err := readTimestamp(serverKeys["valid_until_ts"])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid valid_until_ts: %w", err)
+	}
+	return id.String(), validUntil, nil
+}
+
+// Verifies the signature and expiry of server keys and returns valid_until_ts.
+func verifyServerKeys(id string, value []byte) (int64, error) {
+	decoded, err := decodeJSON(value)
+	if err != nil {
+		return 0, err
+	}
+	serverKeys, ok := decoded.(map[string]any)
+	if !ok {
+		return 0, errors.New("server keys is not a JSON object")
+	}
+	serverID, validUntil, err := verifyServerKeysObject(serverKeys)
+	if err != nil {
+		return 0, err
+	}
+	if serverID != id {
+		return 0, errors.New("server_name does not match record key")
+	}
+	if validUntil < time.Now().UnixMilli() {
+		return 0, errors.New("server keys expired")
+	}
+	return validUntil, nil
+}
+
+// Returns a shallow copy of a JSON object without the given keys.
+func withoutKeys(value map[string]any, keys ...string) map[string]any {
+	result := make(map[string]any, len(value))
+	for k, v := range value {
+		result[k] = v
+	}
+	for _, key := range keys {
+		delete(result, key)
+	}
+	return result
+}
+
+func readTimestamp(value any) (int64, error) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, errors.New("not a number")
+	}
+	return number.Int64()
+}
+
+func (validator serverKeysValidator) Validate(key string, value []byte) error {
+	namespace, id, err := record.SplitKey(key)
+	if err != nil {
+		return err
+	}
+	if namespace != serverKeysNamespace {
+		return fmt.Errorf("unexpected namespace: %s", namespace)
+	}
+	_, err = verifyServerKeys(id, value)
+	return err
+}
+
+// Selects the valid record with the latest valid_until_ts.
+func (validator serverKeysValidator) Select(key string, values [][]byte) (int, error) {
+	_, id, err := record.SplitKey(key)
+	if err != nil {
+		return 0, err
+	}
+	best := -1
+	var bestValidUntil int64
+	for i, value := range values {
+		validUntil, err := verifyServerKeys(id, value)
+		if err != nil {
+			continue
+		}
+		if best == -1 || validUntil > bestValidUntil {
+			best = i
+			bestValidUntil = validUntil
+		}
+	}
+	if best == -1 {
+		return 0, errors.New("no valid server keys record")
+	}
+	return best, nil
+}
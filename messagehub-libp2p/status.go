@@ -0,0 +1,158 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/event"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p/p2p/protocol/holepunch"
+	"github.com/libp2p/go-libp2p/p2p/protocol/identify"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const maxHolePunchResults = 32
+
+type holePunchResult struct {
+	PeerID    string
+	Type      string
+	Success   bool
+	Error     string `json:",omitempty"`
+	Timestamp int64
+}
+
+type networkStatus struct {
+	Reachability      string
+	NATDeviceTypes    map[string]string
+	ListenAddresses   []string
+	ObservedAddresses []string
+	RelayAddresses    []string
+	HolePunches       []holePunchResult
+}
+
+type networkStatusTracker struct {
+	mutex          sync.RWMutex
+	reachability   network.Reachability
+	natDeviceTypes map[network.NATTransportProtocol]network.NATDeviceType
+	holePunches    map[peer.ID]holePunchResult
+	subscription   event.Subscription
+}
+
+func newNetworkStatusTracker() *networkStatusTracker {
+	return &networkStatusTracker{
+		reachability:   network.ReachabilityUnknown,
+		natDeviceTypes: make(map[network.NATTransportProtocol]network.NATDeviceType),
+		holePunches:    make(map[peer.ID]holePunchResult),
+	}
+}
+
+func (tracker *networkStatusTracker) start(host host.Host) error {
+	eventTypes := []any{
+		new(event.EvtLocalReachabilityChanged),
+		new(event.EvtNATDeviceTypeChanged),
+	}
+	subscription, err := host.EventBus().Subscribe(eventTypes)
+	if err != nil {
+		return err
+	}
+	tracker.subscription = subscription
+	go func() {
+		for e := range subscription.Out() {
+			tracker.mutex.Lock()
+			switch e := e.(type) {
+			case event.EvtLocalReachabilityChanged:
+				tracker.reachability = e.Reachability
+			case event.EvtNATDeviceTypeChanged:
+				tracker.natDeviceTypes[e.TransportProtocol] = e.NatDeviceType
+			}
+			tracker.mutex.Unlock()
+		}
+	}()
+	return nil
+}
+
+func (tracker *networkStatusTracker) close() error {
+	if tracker.subscription == nil {
+		return nil
+	}
+	return tracker.subscription.Close()
+}
+
+// Implements holepunch.EventTracer, keeps the latest outcome for each remote peer.
+func (tracker *networkStatusTracker) Trace(evt *holepunch.Event) {
+	result := holePunchResult{
+		PeerID:    peer.Encode(evt.Remote),
+		Type:      evt.Type,
+		Timestamp: time.Unix(0, evt.Timestamp).UnixMilli(),
+	}
+	switch e := evt.Evt.(type) {
+	case *holepunch.DirectDialEvt:
+		result.Success = e.Success
+		result.Error = e.Error
+	case *holepunch.EndHolePunchEvt:
+		result.Success = e.Success
+		result.Error = e.Error
+	case *holepunch.ProtocolErrorEvt:
+		result.Error = e.Error
+	default:
+		return
+	}
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.holePunches[evt.Remote] = result
+	if len(tracker.holePunches) > maxHolePunchResults {
+		var oldestPeer peer.ID
+		var oldest int64
+		for peerID, r := range tracker.holePunches {
+			if oldestPeer == "" || r.Timestamp < oldest {
+				oldestPeer = peerID
+				oldest = r.Timestamp
+			}
+		}
+		delete(tracker.holePunches, oldestPeer)
+	}
+}
+
+func encodeAddrs(addrs []ma.Multiaddr) []string {
+	result := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		result = append(result, addr.String())
+	}
+	return result
+}
+
+func isRelayAddr(addr ma.Multiaddr) bool {
+	_, err := addr.ValueForProtocol(ma.P_CIRCUIT)
+	return err == nil
+}
+
+func (tracker *networkStatusTracker) getStatus(host host.Host) networkStatus {
+	tracker.mutex.RLock()
+	defer tracker.mutex.RUnlock()
+
+	status := networkStatus{
+		Reachability:      tracker.reachability.String(),
+		NATDeviceTypes:    make(map[string]string),
+		ListenAddresses:   encodeAddrs(host.Network().ListenAddresses()),
+		ObservedAddresses: make([]string, 0),
+		RelayAddresses:    encodeAddrs(ma.FilterAddrs(host.Addrs(), isRelayAddr)),
+		HolePunches:       make([]holePunchResult, 0, len(tracker.holePunches)),
+	}
+	for protocol, deviceType := range tracker.natDeviceTypes {
+		status.NATDeviceTypes[protocol.String()] = deviceType.String()
+	}
+	if h, ok := host.(interface{ IDService() identify.IDService }); ok {
+		status.ObservedAddresses = encodeAddrs(h.IDService().OwnObservedAddrs())
+	}
+	for _, result := range tracker.holePunches {
+		status.HolePunches = append(status.HolePunches, result)
+	}
+	sort.Slice(status.HolePunches, func(i, j int) bool {
+		return status.HolePunches[i].Timestamp > status.HolePunches[j].Timestamp
+	})
+	return status
+}
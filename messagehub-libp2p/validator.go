@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+)
+
+// Built-in validator verifying signed requests and the PDUs they carry.
+const signatureValidator = "Signature"
+
+// Key identifier of the peer ID authorized by an identity, see AuthorizedPeer.KeyIdentifier.
+const authorizedPeerKeyIdentifier = "libp2p:PeerID"
+
+var errServerKeysExpired = errors.New("server keys expired")
+
+// Verifies JSON signed with the verify keys of its embedded server_keys, see IdentityServiceExtensions.VerifyJson.
+// Returns the identity ID and its verify keys.
+func verifySignedJSON(value map[string]any) (string, map[string]any, error) {
+	serverKeys, ok := value["server_keys"].(map[string]any)
+	if !ok {
+		return "", nil, errors.New("server_keys not found")
+	}
+	id, validUntil, err := verifyServerKeysObject(serverKeys)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid server_keys: %w", err)
+	}
+	// Signatures stay valid after the keys expire, as long as they were created before.
+	timestamp, err := readTimestamp(value["origin_server_ts"])
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid origin_server_ts: %w", err)
+	}
+	if timestamp > validUntil {
+		return "", nil, errServerKeysExpired
+	}
+	verifyKeys, ok := serverKeys["verify_keys"].(map[string]any)
+	if !ok {
+		return "", nil, errors.New("invalid verify_keys")
+	}
+	signatures, ok := value["signatures"].(map[string]any)
+	if !ok {
+		return "", nil, errors.New("invalid signatures")
+	}
+	identitySignatures, ok := signatures[id].(map[string]any)
+	if !ok {
+		return "", nil, errors.New("identity signature not found")
+	}
+	data, err := encodeCanonicalJSON(withoutKeys(value, "signatures", "unsigned"))
+	if err != nil {
+		return "", nil, err
+	}
+	for keyIdentifier, encodedSignature := range identitySignatures {
+		if !strings.HasPrefix(keyIdentifier, "ed25519:") {
+			continue
+		}
+		encodedKey, ok := verifyKeys[keyIdentifier].(string)
+		if !ok {
+			continue
+		}
+		encodedSignature, ok := encodedSignature.(string)
+		if !ok {
+			continue
+		}
+		publicKey, err := base64.RawStdEncoding.DecodeString(encodedKey)
+		if err != nil || len(publicKey) != ed25519.PublicKeySize {
+			continue
+		}
+		signature, err := base64.RawStdEncoding.DecodeString(encodedSignature)
+		if err != nil {
+			continue
+		}
+		if ed25519.Verify(publicKey, data, signature) {
+			return id, verifyKeys, nil
+		}
+	}
+	return "", nil, errors.New("no valid signature")
+}
+
+func toValidationResult(err error) pubsub.ValidationResult {
+	if errors.Is(err, errServerKeysExpired) {
+		return pubsub.ValidationIgnore
+	}
+	return pubsub.ValidationReject
+}
+
+// Returns the server name of a user ID, see UserIdentifier.TryParse.
+func getUserServerName(userID string) (string, bool) {
+	if !strings.HasPrefix(userID, "@") {
+		return "", false
+	}
+	_, serverName, ok := strings.Cut(userID[1:], ":")
+	return serverName, ok
+}
+
+// Messages are signed requests published by EventPublisher, the origin must match the signing identity
+// and be authorized for the publishing peer, see AuthorizedPeer.Verify. Each PDU in the content must be
+// signed by the identity of its sender, see RoomEventsReceiver.VerifySignature.
+// Returns the verified request.
+func verifyPublishedRequest(from peer.ID, data []byte) (map[string]any, error) {
+	decoded, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	request, ok := decoded.(map[string]any)
+	if !ok {
+		return nil, errors.New("request is not a JSON object")
+	}
+	id, verifyKeys, err := verifySignedJSON(request)
+	if err != nil {
+		return nil, err
+	}
+	if origin, _ := request["origin"].(string); origin != id {
+		return nil, errors.New("origin does not match signing identity")
+	}
+	if authorizedPeer, _ := verifyKeys[authorizedPeerKeyIdentifier].(string); authorizedPeer != peer.Encode(from) {
+		return nil, errors.New("peer not authorized by origin")
+	}
+	content, _ := request["content"].(map[string]any)
+	pdus, _ := content["pdus"].([]any)
+	for _, value := range pdus {
+		pdu, ok := value.(map[string]any)
+		if !ok {
+			return nil, errors.New("PDU is not a JSON object")
+		}
+		signer, _, err := verifySignedJSON(pdu)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PDU: %w", err)
+		}
+		sender, _ := pdu["sender"].(string)
+		if serverName, ok := getUserServerName(sender); !ok || serverName != signer {
+			return nil, errors.New("PDU sender does not match signing identity")
+		}
+	}
+	return request, nil
+}
+
+func validateSignature(_ context.Context, _ peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+	_, err := verifyPublishedRequest(msg.GetFrom(), msg.Data)
+	if err != nil {
+		return toValidationResult(err)
+	}
+	return pubsub.ValidationAccept
+}
+
+func getTopicValidator(name string) (pubsub.ValidatorEx, error) {
+	switch name {
+	case signatureValidator:
+		return validateSignature, nil
+	default:
+		return nil, fmt.Errorf("unknown validator: %s", name)
+	}
+}
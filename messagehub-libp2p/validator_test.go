@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+)
+
+type testIdentity struct {
+	id         string
+	serverName string
+	privateKey ed25519.PrivateKey
+	serverKeys map[string]any
+}
+
+func sign(t *testing.T, privateKey ed25519.PrivateKey, value map[string]any) string {
+	t.Helper()
+	data, err := encodeCanonicalJSON(withoutKeys(value, "signatures", "unsigned"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawStdEncoding.EncodeToString(ed25519.Sign(privateKey, data))
+}
+
+func createTestIdentity(t *testing.T, validUntil int64, authorizedPeer peer.ID) *testIdentity {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := encodeEd25519PublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverName := base64.RawStdEncoding.EncodeToString(publicKey)
+	verifyKeys := map[string]any{serverKeyIdentifier: serverName}
+	if authorizedPeer != "" {
+		verifyKeys[authorizedPeerKeyIdentifier] = peer.Encode(authorizedPeer)
+	}
+	serverKeys := map[string]any{
+		"server_name":    serverName,
+		"valid_until_ts": validUntil,
+		"verify_keys":    verifyKeys,
+	}
+	serverKeys["signatures"] = map[string]any{
+		serverName: map[string]any{serverKeyIdentifier: sign(t, privateKey, serverKeys)},
+	}
+	return &testIdentity{
+		id:         id.String(),
+		serverName: serverName,
+		privateKey: privateKey,
+		serverKeys: serverKeys,
+	}
+}
+
+func (identity *testIdentity) signJSON(t *testing.T, value map[string]any) {
+	t.Helper()
+	value["server_keys"] = identity.serverKeys
+	value["signatures"] = map[string]any{
+		identity.id: map[string]any{serverKeyIdentifier: sign(t, identity.privateKey, value)},
+	}
+}
+
+func createTestPeerID(t *testing.T) peer.ID {
+	t.Helper()
+	privateKey, _, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerID, err := peer.IDFromPrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return peerID
+}
+
+func createSignedPDU(t *testing.T, signer *testIdentity, sender string, timestamp int64) map[string]any {
+	t.Helper()
+	pdu := map[string]any{
+		"room_id":          "!room:example.org",
+		"sender":           sender,
+		"type":             "m.room.message",
+		"content":          map[string]any{"body": "hello"},
+		"origin_server_ts": timestamp,
+		"server_keys":      signer.serverKeys,
+	}
+	// See EventHash.UpdateHash.
+	data, err := encodeCanonicalJSON(pdu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(data)
+	pdu["hashes"] = map[string]any{"sha256": base64.RawStdEncoding.EncodeToString(hash[:])}
+	signer.signJSON(t, pdu)
+	return pdu
+}
+
+func createSignedRequest(t *testing.T, origin *testIdentity, timestamp int64, pdus ...map[string]any) map[string]any {
+	t.Helper()
+	var items []any
+	for _, pdu := range pdus {
+		items = append(items, pdu)
+	}
+	request := map[string]any{
+		"method":           "PUT",
+		"uri":              "/_matrix/federation/v1/send/1",
+		"origin":           origin.id,
+		"origin_server_ts": timestamp,
+		"destination":      "!room:example.org",
+		"content":          map[string]any{"pdus": items},
+	}
+	origin.signJSON(t, request)
+	return request
+}
+
+func validateTestMessage(t *testing.T, from peer.ID, request map[string]any) pubsub.ValidationResult {
+	t.Helper()
+	data, err := json.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &pubsub.Message{Message: &pb.Message{Data: data, From: []byte(from)}}
+	return validateSignature(context.Background(), from, msg)
+}
+
+func TestValidateSignature(t *testing.T) {
+	now := time.Now().UnixMilli()
+	validUntil := now + time.Hour.Milliseconds()
+	peerID := createTestPeerID(t)
+	identity := createTestIdentity(t, validUntil, peerID)
+	other := createTestIdentity(t, validUntil, peerID)
+
+	tests := []struct {
+		name     string
+		from     peer.ID
+		request  func() map[string]any
+		expected pubsub.ValidationResult
+	}{
+		{
+			name: "valid signature",
+			from: peerID,
+			request: func() map[string]any {
+				pdu := createSignedPDU(t, identity, "@alice:"+identity.id, now)
+				return createSignedRequest(t, identity, now, pdu)
+			},
+			expected: pubsub.ValidationAccept,
+		},
+		{
+			name: "PDU from another server",
+			from: peerID,
+			request: func() map[string]any {
+				pdu := createSignedPDU(t, other, "@bob:"+other.id, now)
+				return createSignedRequest(t, identity, now, pdu)
+			},
+			expected: pubsub.ValidationAccept,
+		},
+		{
+			name: "invalid signature",
+			from: peerID,
+			request: func() map[string]any {
+				request := createSignedRequest(t, identity, now)
+				request["destination"] = "!other:example.org"
+				return request
+			},
+			expected: pubsub.ValidationReject,
+		},
+		{
+			name: "invalid PDU signature",
+			from: peerID,
+			request: func() map[string]any {
+				pdu := createSignedPDU(t, identity, "@alice:"+identity.id, now)
+				pdu["content"] = map[string]any{"body": "forged"}
+				return createSignedRequest(t, identity, now, pdu)
+			},
+			expected: pubsub.ValidationReject,
+		},
+		{
+			name: "expired server keys",
+			from: peerID,
+			request: func() map[string]any {
+				expired := createTestIdentity(t, now-1, peerID)
+				return createSignedRequest(t, expired, now)
+			},
+			expected: pubsub.ValidationIgnore,
+		},
+		{
+			name: "missing origin_server_ts",
+			from: peerID,
+			request: func() map[string]any {
+				request := map[string]any{"origin": identity.id, "content": map[string]any{}}
+				identity.signJSON(t, request)
+				return request
+			},
+			expected: pubsub.ValidationReject,
+		},
+		{
+			name: "sender of another server",
+			from: peerID,
+			request: func() map[string]any {
+				pdu := createSignedPDU(t, identity, "@bob:"+other.id, now)
+				return createSignedRequest(t, identity, now, pdu)
+			},
+			expected: pubsub.ValidationReject,
+		},
+		{
+			name: "missing authorized peer",
+			from: peerID,
+			request: func() map[string]any {
+				unauthorized := createTestIdentity(t, validUntil, "")
+				return createSignedRequest(t, unauthorized, now)
+			},
+			expected: pubsub.ValidationReject,
+		},
+		{
+			name: "published by another peer",
+			from: createTestPeerID(t),
+			request: func() map[string]any {
+				return createSignedRequest(t, identity, now)
+			},
+			expected: pubsub.ValidationReject,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := validateTestMessage(t, test.from, test.request())
+			if result != test.expected {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}